@@ -0,0 +1,153 @@
+// Package ast 定义了 TOON 源码的抽象语法树，记录每个节点在源文件中的
+// 位置信息，设计上参照 go/ast：节点实现 Node 接口，Walk 按深度优先顺序
+// 遍历一棵树，遍历的继续/终止由 Visitor 的返回值决定。
+package ast
+
+import "fmt"
+
+// Position 描述源码中的一个位置，字段含义与 go/scanner.Position 一致。
+type Position struct {
+	Offset int // 从文件开头起的字节偏移
+	Line   int // 行号，从1开始
+	Column int // 列号，从1开始
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Node 是AST中所有节点都实现的接口。
+type Node interface {
+	Pos() Position // 节点的起始位置
+	End() Position // 节点的结束位置：对象/数组是其右括号本身的位置，标量是其后下一个 token 的起始位置
+}
+
+// Document 是一个完整TOON文档的根节点。
+type Document struct {
+	Root     Node
+	StartPos Position
+	EndPos   Position
+	Lead     *CommentGroup // 文件开头、根值之前的注释
+}
+
+func (d *Document) Pos() Position { return d.StartPos }
+func (d *Document) End() Position { return d.EndPos }
+
+// Object 是一个 `{ ... }` 对象节点，Fields 保留源码中的书写顺序。
+type Object struct {
+	Fields []*Field
+	Lbrace Position
+	Rbrace Position
+}
+
+func (o *Object) Pos() Position { return o.Lbrace }
+func (o *Object) End() Position { return o.Rbrace }
+
+// Field 是对象中的一个 `key: value` 条目。Lead 是紧邻在字段之前、独占一
+// 行的注释块；Line 是跟在字段值（或其后的逗号）同一行的行尾注释。
+type Field struct {
+	Key    string
+	KeyPos Position
+	Colon  Position
+	Value  Node
+	Lead   *CommentGroup
+	Line   *CommentGroup
+}
+
+func (f *Field) Pos() Position { return f.KeyPos }
+func (f *Field) End() Position { return f.Value.End() }
+
+// Array 是一个以展开形式（逐行一个元素）编码的数组。
+type Array struct {
+	Elems   []Node
+	Size    int // 头部 `[ N` 里记录的大小
+	HasSize bool
+	Lbrack  Position
+	Rbrack  Position
+}
+
+func (a *Array) Pos() Position { return a.Lbrack }
+func (a *Array) End() Position { return a.Rbrack }
+
+// TabularArray 是一个 `[ N {k1, k2}: ...]` 表格形式的数组，Keys 保留列
+// 的书写顺序，Rows 里每行的单元格与 Keys 按下标对应。RowComments（若非
+// nil）与 Rows 等长，RowComments[i] 是第 i 行行尾的同行注释。
+type TabularArray struct {
+	Keys        []string
+	Rows        [][]Node
+	RowComments []*CommentGroup
+	Size        int
+	Lbrack      Position
+	Header      Position // 表头 `{` 的位置
+	Rbrack      Position
+}
+
+func (a *TabularArray) Pos() Position { return a.Lbrack }
+func (a *TabularArray) End() Position { return a.Rbrack }
+
+// ScalarKind 标记一个标量节点的种类。
+type ScalarKind int
+
+const (
+	String ScalarKind = iota
+	Number
+	Bool
+	Null
+)
+
+// Scalar 是一个标量值节点：字符串、数字、布尔或 null。Raw 保留了源码中
+// 未解释的原始文本（例如带引号的字符串），供格式化/回写场景使用。
+type Scalar struct {
+	Kind     ScalarKind
+	Value    interface{}
+	Raw      string
+	StartPos Position
+	EndPos   Position
+}
+
+func (s *Scalar) Pos() Position { return s.StartPos }
+func (s *Scalar) End() Position { return s.EndPos }
+
+// Visitor 的 Visit 方法对每个被访问的节点调用一次；若返回值 w 非nil，
+// Walk 会用 w 继续遍历 node 的子节点，遍历完成后再调用 w.Visit(nil)。
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 以深度优先顺序遍历AST，行为模仿 go/ast.Walk。
+func Walk(v Visitor, node Node) {
+	if node == nil {
+		return
+	}
+	v = v.Visit(node)
+	if v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Document:
+		Walk(v, n.Root)
+	case *Object:
+		for _, f := range n.Fields {
+			Walk(v, f)
+		}
+	case *Field:
+		Walk(v, n.Value)
+	case *Array:
+		for _, e := range n.Elems {
+			Walk(v, e)
+		}
+	case *TabularArray:
+		for _, row := range n.Rows {
+			for _, cell := range row {
+				Walk(v, cell)
+			}
+		}
+	case *Scalar:
+		// 没有子节点
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
@@ -0,0 +1,195 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseASTObjectPreservesOrderAndPositions(t *testing.T) {
+	doc, err := ParseAST(`{
+  name: "Alice",
+  age: 30
+}`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	obj, ok := doc.Root.(*Object)
+	if !ok {
+		t.Fatalf("expected *Object root, got %T", doc.Root)
+	}
+	if len(obj.Fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(obj.Fields))
+	}
+	if obj.Fields[0].Key != "name" || obj.Fields[1].Key != "age" {
+		t.Errorf("expected field order [name, age], got [%s, %s]", obj.Fields[0].Key, obj.Fields[1].Key)
+	}
+	if obj.Fields[0].KeyPos.Line != 2 {
+		t.Errorf("expected name field on line 2, got %d", obj.Fields[0].KeyPos.Line)
+	}
+}
+
+func TestParseASTTabularArray(t *testing.T) {
+	doc, err := ParseAST(`[ 2 {id, name}:
+  1, "Alice"
+  2, "Bob"
+]`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	tab, ok := doc.Root.(*TabularArray)
+	if !ok {
+		t.Fatalf("expected *TabularArray root, got %T", doc.Root)
+	}
+	if !reflect.DeepEqual(tab.Keys, []string{"id", "name"}) {
+		t.Errorf("expected keys [id name], got %v", tab.Keys)
+	}
+	if len(tab.Rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(tab.Rows))
+	}
+}
+
+func TestParseASTPlainArrayWithSizePrefix(t *testing.T) {
+	// A size prefix not followed by a tabular header (the shape the
+	// encoder itself emits for a plain array when ShowArraySizes is on)
+	// must still be skipped, not treated as the first literal element.
+	doc, err := ParseAST(`[ 3
+  1,
+  2,
+  3
+]`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	if _, ok := doc.Root.(*Array); !ok {
+		t.Fatalf("expected *Array root, got %T", doc.Root)
+	}
+	got := MaterializeAST(doc)
+	want := []interface{}{int64(1), int64(2), int64(3)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMaterializeASTMatchesDecode(t *testing.T) {
+	doc, err := ParseAST(`{
+  users: [ 2 {id, name}:
+    1, "Alice"
+    2, "Bob"
+  ]
+}`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	got := MaterializeAST(doc)
+	expected := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"id": int64(1), "name": "Alice"},
+			map[string]interface{}{"id": int64(2), "name": "Bob"},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestWalkVisitsEveryNode(t *testing.T) {
+	doc, err := ParseAST(`{a: [1, 2]}`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	var kinds []string
+	var v visitorFunc
+	v = func(n Node) Visitor {
+		if n == nil {
+			return nil
+		}
+		switch n.(type) {
+		case *Object:
+			kinds = append(kinds, "object")
+		case *Field:
+			kinds = append(kinds, "field")
+		case *Array:
+			kinds = append(kinds, "array")
+		case *Scalar:
+			kinds = append(kinds, "scalar")
+		}
+		return v
+	}
+	Walk(v, doc.Root)
+
+	expected := []string{"object", "field", "array", "scalar", "scalar"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected visit order %v, got %v", expected, kinds)
+	}
+}
+
+type visitorFunc func(n Node) Visitor
+
+func (f visitorFunc) Visit(n Node) Visitor { return f(n) }
+
+func TestParseASTAttachesLeadingAndLineComments(t *testing.T) {
+	doc, err := ParseAST(`{
+  # describes the user's name
+  name: "Alice", # inline note
+  age: 30
+}`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	obj := doc.Root.(*Object)
+	name := obj.Fields[0]
+	if name.Lead == nil || name.Lead.Text() != "describes the user's name" {
+		t.Errorf("expected leading comment on name field, got %v", name.Lead)
+	}
+	if name.Line == nil || name.Line.Text() != "inline note" {
+		t.Errorf("expected line comment on name field, got %v", name.Line)
+	}
+	if obj.Fields[1].Lead != nil || obj.Fields[1].Line != nil {
+		t.Errorf("expected no comments on age field, got Lead=%v Line=%v", obj.Fields[1].Lead, obj.Fields[1].Line)
+	}
+}
+
+func TestParseASTBlankLineSplitsLeadCommentGroups(t *testing.T) {
+	doc, err := ParseAST(`{
+  # group A
+
+  # group B
+  name: "Alice"
+}`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	obj := doc.Root.(*Object)
+	name := obj.Fields[0]
+	if name.Lead == nil || name.Lead.Text() != "group B" {
+		t.Errorf("expected lead comment to be only the block adjacent to the field (group B), got %v", name.Lead)
+	}
+}
+
+func TestParseASTAttachesTabularRowComments(t *testing.T) {
+	doc, err := ParseAST(`[ 2 {id, name}:
+  1, "Alice" # admin
+  2, "Bob"
+]`)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	tab := doc.Root.(*TabularArray)
+	if len(tab.RowComments) != 2 {
+		t.Fatalf("expected 2 row comment slots, got %d", len(tab.RowComments))
+	}
+	if tab.RowComments[0] == nil || tab.RowComments[0].Text() != "admin" {
+		t.Errorf("expected row 0 comment \"admin\", got %v", tab.RowComments[0])
+	}
+	if tab.RowComments[1] != nil {
+		t.Errorf("expected no comment on row 1, got %v", tab.RowComments[1])
+	}
+}
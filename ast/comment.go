@@ -0,0 +1,44 @@
+package ast
+
+import "strings"
+
+// Comment 是一条注释，Text 保留包括定界符在内的原始文本（`# ...` 或
+// `/* ... */`）。
+type Comment struct {
+	Text     string
+	StartPos Position
+	EndPos   Position
+}
+
+// CommentGroup 是一组相邻的注释（之间没有空行），设计参照 go/ast。
+type CommentGroup struct {
+	List []*Comment
+}
+
+// Text 返回注释正文，已去掉 `#`、`/*`、`*/` 定界符并 trim 首尾空白，
+// 多条注释之间用换行连接。
+func (g *CommentGroup) Text() string {
+	if g == nil || len(g.List) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i, c := range g.List {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(stripCommentDelimiters(c.Text))
+	}
+	return b.String()
+}
+
+func stripCommentDelimiters(text string) string {
+	switch {
+	case strings.HasPrefix(text, "#"):
+		return strings.TrimSpace(strings.TrimPrefix(text, "#"))
+	case strings.HasPrefix(text, "/*"):
+		inner := strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		return strings.TrimSpace(inner)
+	default:
+		return text
+	}
+}
@@ -0,0 +1,530 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseAST 把 input 解析为一棵TOON抽象语法树，保留表格/展开两种数组
+// 形式、字段书写顺序、每个节点的位置信息，以及 `#` 行注释和 `/* ... */`
+// 块注释，供源码保留式的改写、校验或格式化工具使用。
+func ParseAST(input string) (*Document, error) {
+	l := newASTLexer(input)
+	p := &astParser{lexer: l}
+	p.next()
+	lead := p.takeLead()
+	p.next()
+
+	startPos := p.cur.pos
+	root, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{Root: root, StartPos: startPos, EndPos: p.cur.pos, Lead: lead}, nil
+}
+
+// MaterializeAST 把一棵AST还原为 gotoon.Decode 产出的通用值
+// （map[string]interface{}、[]interface{}、标量），会丢弃位置信息以及
+// 表格/展开两种数组形式的区分，对应解析前的“当前行为”。
+func MaterializeAST(doc *Document) interface{} {
+	if doc == nil {
+		return nil
+	}
+	return materialize(doc.Root)
+}
+
+func materialize(n Node) interface{} {
+	switch v := n.(type) {
+	case *Object:
+		m := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			m[f.Key] = materialize(f.Value)
+		}
+		return m
+	case *Array:
+		arr := make([]interface{}, len(v.Elems))
+		for i, e := range v.Elems {
+			arr[i] = materialize(e)
+		}
+		return arr
+	case *TabularArray:
+		arr := make([]interface{}, len(v.Rows))
+		for i, row := range v.Rows {
+			m := make(map[string]interface{}, len(v.Keys))
+			for j, key := range v.Keys {
+				if j < len(row) {
+					m[key] = materialize(row[j])
+				}
+			}
+			arr[i] = m
+		}
+		return arr
+	case *Scalar:
+		return v.Value
+	}
+	return nil
+}
+
+// ------------------------------
+// 内部词法/语法分析
+//
+// 与 gotoon 包里的词法/语法分析并行存在，但始终按字节逐步扫描并记录
+// 行列位置，供AST节点的 Pos/End 使用。
+// ------------------------------
+
+type astTokenType int
+
+const (
+	astEOF astTokenType = iota
+	astLBrace
+	astRBrace
+	astLBracket
+	astRBracket
+	astColon
+	astComma
+	astNumber
+	astString
+	astBoolean
+	astNull
+	astIdentifier
+	astComment
+)
+
+type astToken struct {
+	typ   astTokenType
+	value string
+	pos   Position
+}
+
+type astLexer struct {
+	input string
+	pos   int
+	line  int
+	col   int
+}
+
+func newASTLexer(input string) *astLexer {
+	return &astLexer{input: input, line: 1, col: 1}
+}
+
+func (l *astLexer) peekByte() byte {
+	if l.pos >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos]
+}
+
+func (l *astLexer) advance() byte {
+	c := l.input[l.pos]
+	l.pos++
+	if c == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return c
+}
+
+func (l *astLexer) skipWhitespace() {
+	for l.pos < len(l.input) && unicode.IsSpace(rune(l.input[l.pos])) {
+		l.advance()
+	}
+}
+
+func (l *astLexer) position() Position {
+	return Position{Offset: l.pos, Line: l.line, Column: l.col}
+}
+
+func (l *astLexer) nextToken() astToken {
+	l.skipWhitespace()
+	start := l.position()
+
+	if l.pos >= len(l.input) {
+		return astToken{typ: astEOF, pos: start}
+	}
+
+	switch c := l.peekByte(); c {
+	case '{':
+		l.advance()
+		return astToken{typ: astLBrace, value: "{", pos: start}
+	case '}':
+		l.advance()
+		return astToken{typ: astRBrace, value: "}", pos: start}
+	case '[':
+		l.advance()
+		return astToken{typ: astLBracket, value: "[", pos: start}
+	case ']':
+		l.advance()
+		return astToken{typ: astRBracket, value: "]", pos: start}
+	case ':':
+		l.advance()
+		return astToken{typ: astColon, value: ":", pos: start}
+	case ',':
+		l.advance()
+		return astToken{typ: astComma, value: ",", pos: start}
+	case '"':
+		return l.scanString(start)
+	case '#':
+		return l.scanLineComment(start)
+	case '/':
+		if l.pos+1 < len(l.input) && l.input[l.pos+1] == '*' {
+			return l.scanBlockComment(start)
+		}
+		l.advance()
+		return astToken{typ: astEOF, pos: start}
+	default:
+		if c == '-' || (c >= '0' && c <= '9') {
+			return l.scanNumber(start)
+		}
+		if isAlpha(c) {
+			return l.scanIdentifier(start)
+		}
+		l.advance()
+		return astToken{typ: astEOF, pos: start}
+	}
+}
+
+// scanLineComment 扫描一个 `# ...` 行注释，不包含结尾的换行符。
+func (l *astLexer) scanLineComment(start Position) astToken {
+	begin := l.pos
+	for l.pos < len(l.input) && l.peekByte() != '\n' {
+		l.advance()
+	}
+	return astToken{typ: astComment, value: l.input[begin:l.pos], pos: start}
+}
+
+// scanBlockComment 扫描一个 `/* ... */` 块注释，包含起止定界符，允许
+// 跨行。
+func (l *astLexer) scanBlockComment(start Position) astToken {
+	begin := l.pos
+	l.advance() // '/'
+	l.advance() // '*'
+	for l.pos < len(l.input) {
+		if l.peekByte() == '*' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '/' {
+			l.advance()
+			l.advance()
+			break
+		}
+		l.advance()
+	}
+	return astToken{typ: astComment, value: l.input[begin:l.pos], pos: start}
+}
+
+func (l *astLexer) scanString(start Position) astToken {
+	begin := l.pos
+	l.advance() // 起始引号
+	for l.pos < len(l.input) {
+		c := l.peekByte()
+		if c == '"' {
+			l.advance()
+			break
+		}
+		if c == '\\' {
+			l.advance()
+			if l.pos < len(l.input) {
+				l.advance()
+			}
+			continue
+		}
+		l.advance()
+	}
+	return astToken{typ: astString, value: l.input[begin:l.pos], pos: start}
+}
+
+func (l *astLexer) scanNumber(start Position) astToken {
+	begin := l.pos
+	l.advance() // 符号或首位数字
+	for l.pos < len(l.input) {
+		switch c := l.peekByte(); {
+		case c >= '0' && c <= '9':
+			l.advance()
+		case c == '.' || c == 'e' || c == 'E':
+			l.advance()
+			if l.pos < len(l.input) && (l.peekByte() == '+' || l.peekByte() == '-') {
+				l.advance()
+			}
+		default:
+			return astToken{typ: astNumber, value: l.input[begin:l.pos], pos: start}
+		}
+	}
+	return astToken{typ: astNumber, value: l.input[begin:l.pos], pos: start}
+}
+
+func (l *astLexer) scanIdentifier(start Position) astToken {
+	begin := l.pos
+	for l.pos < len(l.input) && isAlphaNumeric(l.peekByte()) {
+		l.advance()
+	}
+	val := l.input[begin:l.pos]
+	switch val {
+	case "true", "false":
+		return astToken{typ: astBoolean, value: val, pos: start}
+	case "null":
+		return astToken{typ: astNull, value: val, pos: start}
+	default:
+		return astToken{typ: astIdentifier, value: val, pos: start}
+	}
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isAlphaNumeric(c byte) bool {
+	return isAlpha(c) || (c >= '0' && c <= '9')
+}
+
+// astParser 的注释附着规则：扫描下一个有意义的 token 时顺带消费遇到的
+// 注释——与刚刚确定的 cur 同一行的，记在 trailing 里（它是 cur 的行尾
+// 注释）；其余（独占一行的）记在 pendingLead 里，留给接下来解析的节点
+// 当作前置注释块，做法参照 go/parser 对注释的处理。
+type astParser struct {
+	lexer *astLexer
+	cur   astToken
+	peek  astToken
+
+	pendingLead []*Comment
+	trailing    *CommentGroup
+}
+
+func (p *astParser) next() {
+	p.cur = p.peek
+	p.peek = p.nextSignificant()
+}
+
+// nextSignificant 从底层词法分析器取下一个非注释 token，途中把遇到的
+// 注释分类到 pendingLead 或 trailing。
+func (p *astParser) nextSignificant() astToken {
+	for {
+		tok := p.lexer.nextToken()
+		if tok.typ != astComment {
+			return tok
+		}
+		p.absorbComment(tok)
+	}
+}
+
+func (p *astParser) absorbComment(tok astToken) {
+	c := &Comment{Text: tok.value, StartPos: tok.pos, EndPos: p.lexer.position()}
+	if p.cur.typ != astEOF && tok.pos.Line == p.cur.pos.Line {
+		if p.trailing == nil {
+			p.trailing = &CommentGroup{}
+		}
+		p.trailing.List = append(p.trailing.List, c)
+		return
+	}
+	if len(p.pendingLead) > 0 {
+		last := p.pendingLead[len(p.pendingLead)-1]
+		if tok.pos.Line > last.EndPos.Line+1 {
+			// 空行把之前攒的注释块和这一条分开——只有紧邻节点、中间没有
+			// 空行的那一组才会附着成 Lead，之前的块就此丢弃。
+			p.pendingLead = nil
+		}
+	}
+	p.pendingLead = append(p.pendingLead, c)
+}
+
+// takeLead 取走并清空当前累积的前置注释块（如果有）。
+func (p *astParser) takeLead() *CommentGroup {
+	if len(p.pendingLead) == 0 {
+		return nil
+	}
+	g := &CommentGroup{List: p.pendingLead}
+	p.pendingLead = nil
+	return g
+}
+
+// takeTrailing 取走并清空当前累积的行尾注释（如果有）。
+func (p *astParser) takeTrailing() *CommentGroup {
+	g := p.trailing
+	p.trailing = nil
+	return g
+}
+
+func (p *astParser) parseValue() (Node, error) {
+	switch p.cur.typ {
+	case astLBrace:
+		return p.parseObject()
+	case astLBracket:
+		return p.parseArray()
+	case astString:
+		start, raw := p.cur.pos, p.cur.value
+		val, err := strconv.Unquote(raw)
+		if err != nil {
+			val = raw
+		}
+		p.next()
+		return &Scalar{Kind: String, Value: val, Raw: raw, StartPos: start, EndPos: p.cur.pos}, nil
+	case astNumber:
+		start, raw := p.cur.pos, p.cur.value
+		var val interface{}
+		if strings.ContainsAny(raw, ".eE") {
+			f, _ := strconv.ParseFloat(raw, 64)
+			val = f
+		} else {
+			n, _ := strconv.ParseInt(raw, 10, 64)
+			val = n
+		}
+		p.next()
+		return &Scalar{Kind: Number, Value: val, Raw: raw, StartPos: start, EndPos: p.cur.pos}, nil
+	case astBoolean:
+		start, raw := p.cur.pos, p.cur.value
+		p.next()
+		return &Scalar{Kind: Bool, Value: raw == "true", Raw: raw, StartPos: start, EndPos: p.cur.pos}, nil
+	case astNull:
+		start, raw := p.cur.pos, p.cur.value
+		p.next()
+		return &Scalar{Kind: Null, Value: nil, Raw: raw, StartPos: start, EndPos: p.cur.pos}, nil
+	case astIdentifier:
+		start, raw := p.cur.pos, p.cur.value
+		p.next()
+		return &Scalar{Kind: String, Value: raw, Raw: raw, StartPos: start, EndPos: p.cur.pos}, nil
+	default:
+		return nil, fmt.Errorf("ast: unexpected token at %s", p.cur.pos)
+	}
+}
+
+func (p *astParser) parseObject() (*Object, error) {
+	obj := &Object{Lbrace: p.cur.pos}
+	p.next() // 跳过 {
+
+	for p.cur.typ != astRBrace && p.cur.typ != astEOF {
+		lead := p.takeLead()
+
+		if p.cur.typ != astIdentifier && p.cur.typ != astString {
+			return nil, fmt.Errorf("ast: expected key at %s", p.cur.pos)
+		}
+
+		keyPos, key := p.cur.pos, p.cur.value
+		if p.cur.typ == astString {
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			}
+		}
+		p.next()
+
+		if p.cur.typ != astColon {
+			return nil, fmt.Errorf("ast: expected ':' at %s", p.cur.pos)
+		}
+		colonPos := p.cur.pos
+		p.next()
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		line := p.takeTrailing()
+
+		obj.Fields = append(obj.Fields, &Field{Key: key, KeyPos: keyPos, Colon: colonPos, Value: value, Lead: lead, Line: line})
+
+		if p.cur.typ == astComma {
+			p.next()
+		}
+	}
+
+	if p.cur.typ != astRBrace {
+		return nil, fmt.Errorf("ast: unclosed object starting at %s", obj.Lbrace)
+	}
+	obj.Rbrace = p.cur.pos
+	p.next() // 跳过 }
+
+	return obj, nil
+}
+
+func (p *astParser) parseArray() (Node, error) {
+	lbrack := p.cur.pos
+	p.next() // 跳过 [
+
+	// 一个数字只有在它不是紧跟着逗号或 `]`（也就是说它不是数组里的一个
+	// 字面量元素）时，才当作大小前缀处理并跳过——这与 tabular 头部和
+	// 普通数组各自的大小前缀写法都相符。
+	size, hasSize := 0, false
+	if p.cur.typ == astNumber && p.peek.typ != astComma && p.peek.typ != astRBracket {
+		if n, err := strconv.Atoi(p.cur.value); err == nil {
+			size, hasSize = n, true
+		}
+		p.next()
+	}
+
+	if p.cur.typ == astLBrace {
+		return p.parseTabularArray(lbrack, size)
+	}
+
+	arr := &Array{Size: size, HasSize: hasSize, Lbrack: lbrack}
+	for p.cur.typ != astRBracket && p.cur.typ != astEOF {
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr.Elems = append(arr.Elems, val)
+		if p.cur.typ == astComma {
+			p.next()
+		}
+	}
+	if p.cur.typ != astRBracket {
+		return nil, fmt.Errorf("ast: unclosed array starting at %s", lbrack)
+	}
+	arr.Rbrack = p.cur.pos
+	p.next() // 跳过 ]
+	return arr, nil
+}
+
+func (p *astParser) parseTabularArray(lbrack Position, size int) (Node, error) {
+	header := p.cur.pos
+	p.next() // 跳过 {
+
+	var keys []string
+	for p.cur.typ != astRBrace && p.cur.typ != astEOF {
+		switch p.cur.typ {
+		case astIdentifier:
+			keys = append(keys, p.cur.value)
+			p.next()
+		case astString:
+			key, _ := strconv.Unquote(p.cur.value)
+			keys = append(keys, key)
+			p.next()
+		}
+		if p.cur.typ == astComma {
+			p.next()
+		}
+	}
+	if p.cur.typ == astRBrace {
+		p.next() // 跳过 }
+	}
+	if p.cur.typ == astColon {
+		p.next() // 跳过 :
+	}
+
+	tab := &TabularArray{Keys: keys, Size: size, Lbrack: lbrack, Header: header}
+	for p.cur.typ != astRBracket && p.cur.typ != astEOF {
+		var row []Node
+		for i := 0; i < len(keys); i++ {
+			if p.cur.typ == astRBracket || p.cur.typ == astEOF {
+				break
+			}
+			val, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, val)
+			if i < len(keys)-1 && p.cur.typ == astComma {
+				p.next()
+			}
+		}
+		if len(row) == 0 {
+			break
+		}
+		tab.Rows = append(tab.Rows, row)
+		tab.RowComments = append(tab.RowComments, p.takeTrailing())
+	}
+	if p.cur.typ != astRBracket {
+		return nil, fmt.Errorf("ast: unclosed array starting at %s", lbrack)
+	}
+	tab.Rbrack = p.cur.pos
+	p.next() // 跳过 ]
+	return tab, nil
+}
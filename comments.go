@@ -0,0 +1,179 @@
+package gotoon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xxjwxc/gotoon/ast"
+)
+
+// StripComments 解析 input 中的TOON文档并重新编码，丢弃所有 `#` 行注释
+// 和 `/* ... */` 块注释，只保留数据本身，供只关心数据、不关心文档性
+// 注释的调用方使用。
+func StripComments(input string) (string, error) {
+	doc, err := ast.ParseAST(input)
+	if err != nil {
+		return "", err
+	}
+	return Encode(ast.MaterializeAST(doc), DefaultOptions())
+}
+
+// EncodeAST 把一棵 gotoon/ast 语法树重新编码为TOON文本，保留原始的表格
+// /展开数组形式与字段顺序。当 options.PreserveComments 为 true 时，节点
+// 上附着的注释会被输出在原来的位置：字段的前置注释独占一行写在字段
+// 之前，行尾注释（包括表格数组每一行的行尾注释）写在同一输出行内，
+// 从而实现手写TOON文档（含注释）的无损往返。
+func EncodeAST(doc *ast.Document, options Options) (string, error) {
+	var b strings.Builder
+	if options.PreserveComments && doc.Lead != nil {
+		writeASTCommentGroup(&b, doc.Lead, 0, options)
+	}
+	if err := encodeASTNode(doc.Root, options, 0, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func encodeASTNode(n ast.Node, options Options, indent int, b *strings.Builder) error {
+	switch v := n.(type) {
+	case *ast.Object:
+		return encodeASTObject(v, options, indent, b)
+	case *ast.Array:
+		return encodeASTArray(v, options, indent, b)
+	case *ast.TabularArray:
+		return encodeASTTabular(v, options, indent, b)
+	case *ast.Scalar:
+		b.WriteString(formatASTScalar(v))
+		return nil
+	default:
+		return fmt.Errorf("gotoon: unsupported AST node %T", n)
+	}
+}
+
+func formatASTScalar(s *ast.Scalar) string {
+	switch s.Kind {
+	case ast.String:
+		return fmt.Sprintf("%q", s.Value)
+	case ast.Bool:
+		return fmt.Sprintf("%t", s.Value)
+	case ast.Null:
+		return "null"
+	case ast.Number:
+		switch n := s.Value.(type) {
+		case int64:
+			return fmt.Sprintf("%d", n)
+		case float64:
+			return fmt.Sprintf("%g", n)
+		}
+	}
+	return s.Raw
+}
+
+func encodeASTObject(o *ast.Object, options Options, indent int, b *strings.Builder) error {
+	writeIndent(b, indent, options)
+	b.WriteString("{")
+	if len(o.Fields) > 0 {
+		b.WriteString("\n")
+	}
+
+	for i, f := range o.Fields {
+		if options.PreserveComments && f.Lead != nil {
+			writeASTCommentGroup(b, f.Lead, indent+options.IndentSize, options)
+		}
+		writeIndent(b, indent+options.IndentSize, options)
+		b.WriteString(fmt.Sprintf("%s: ", f.Key))
+		if err := encodeASTNode(f.Value, options, indent+options.IndentSize, b); err != nil {
+			return err
+		}
+		if i < len(o.Fields)-1 {
+			b.WriteString(",")
+		}
+		if options.PreserveComments && f.Line != nil {
+			for _, c := range f.Line.List {
+				b.WriteString(" ")
+				b.WriteString(c.Text)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if len(o.Fields) > 0 {
+		writeIndent(b, indent, options)
+	}
+	b.WriteString("}")
+	return nil
+}
+
+func encodeASTArray(a *ast.Array, options Options, indent int, b *strings.Builder) error {
+	b.WriteString("[")
+	if options.ShowArraySizes {
+		b.WriteString(fmt.Sprintf(" %d ", len(a.Elems)))
+	}
+	if len(a.Elems) > 0 {
+		b.WriteString("\n")
+	}
+
+	for i, e := range a.Elems {
+		writeIndent(b, indent+options.IndentSize, options)
+		if err := encodeASTNode(e, options, indent+options.IndentSize, b); err != nil {
+			return err
+		}
+		if i < len(a.Elems)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(a.Elems) > 0 {
+		writeIndent(b, indent, options)
+	}
+	b.WriteString("]")
+	return nil
+}
+
+// encodeASTTabular 编码一个表格数组。表头 `[ N {k1, k2}:` 之后的每一行
+// 独占一行；如果该行在原文里带有行尾注释且 PreserveComments 开启，注释
+// 会被追加在同一输出行内，而不是另起一行。
+func encodeASTTabular(t *ast.TabularArray, options Options, indent int, b *strings.Builder) error {
+	b.WriteString("[")
+	if options.ShowArraySizes {
+		b.WriteString(fmt.Sprintf(" %d ", len(t.Rows)))
+	}
+	b.WriteString("{")
+	b.WriteString(strings.Join(t.Keys, options.Delimiter))
+	b.WriteString("}:\n")
+
+	for i, row := range t.Rows {
+		writeIndent(b, indent+options.IndentSize, options)
+		cells := make([]string, 0, len(row))
+		for _, cell := range row {
+			if s, ok := cell.(*ast.Scalar); ok {
+				cells = append(cells, formatASTScalar(s))
+			}
+		}
+		b.WriteString(strings.Join(cells, options.Delimiter))
+
+		if options.PreserveComments && i < len(t.RowComments) && t.RowComments[i] != nil {
+			for _, c := range t.RowComments[i].List {
+				b.WriteString(" ")
+				b.WriteString(c.Text)
+			}
+		}
+		if i < len(t.Rows)-1 {
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	writeIndent(b, indent, options)
+	b.WriteString("]")
+	return nil
+}
+
+func writeASTCommentGroup(b *strings.Builder, g *ast.CommentGroup, indent int, options Options) {
+	for _, c := range g.List {
+		writeIndent(b, indent, options)
+		b.WriteString(c.Text)
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,88 @@
+package gotoon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xxjwxc/gotoon/ast"
+)
+
+func TestStripComments(t *testing.T) {
+	input := `{
+  # a comment about name
+  name: "Alice", # inline
+  age: 30
+}`
+
+	stripped, err := StripComments(input)
+	if err != nil {
+		t.Fatalf("StripComments failed: %v", err)
+	}
+	if strings.Contains(stripped, "#") {
+		t.Errorf("expected no comments in output, got:\n%s", stripped)
+	}
+
+	data, err := Decode(stripped)
+	if err != nil {
+		t.Fatalf("Decode of stripped output failed: %v", err)
+	}
+	m, ok := data.(map[string]interface{})
+	if !ok || m["name"] != "Alice" {
+		t.Errorf("expected data to be preserved, got %v", data)
+	}
+}
+
+func TestEncodeASTPreservesComments(t *testing.T) {
+	input := `{
+  # a comment about name
+  name: "Alice", # inline
+  age: 30
+}`
+
+	doc, err := ast.ParseAST(input)
+	if err != nil {
+		t.Fatalf("ParseAST failed: %v", err)
+	}
+
+	options := DefaultOptions()
+	options.PreserveComments = true
+	out, err := EncodeAST(doc, options)
+	if err != nil {
+		t.Fatalf("EncodeAST failed: %v", err)
+	}
+
+	lines := strings.Split(out, "\n")
+	var leadLine, nameLine, ageLine int = -1, -1, -1
+	for i, l := range lines {
+		switch {
+		case strings.Contains(l, "# a comment about name"):
+			leadLine = i
+		case strings.Contains(l, "name:"):
+			nameLine = i
+		case strings.Contains(l, "age:"):
+			ageLine = i
+		}
+	}
+	if leadLine == -1 || nameLine == -1 {
+		t.Fatalf("expected both leading comment and name field in output, got:\n%s", out)
+	}
+	if leadLine != nameLine-1 {
+		t.Errorf("expected leading comment directly above name field, got comment on line %d, name on line %d:\n%s", leadLine, nameLine, out)
+	}
+	if !strings.Contains(lines[nameLine], "# inline") {
+		t.Errorf("expected line comment on the same line as name field, got:\n%s", out)
+	}
+	if ageLine != -1 && strings.Contains(lines[ageLine], "#") {
+		t.Errorf("expected no comment attached to age field, got:\n%s", out)
+	}
+
+	// 去掉注释后数据应该保持不变。
+	redecoded, err := Decode(out)
+	if err != nil {
+		t.Fatalf("Decode of round-tripped output failed: %v", err)
+	}
+	m := redecoded.(map[string]interface{})
+	if m["name"] != "Alice" || m["age"] != int64(30) {
+		t.Errorf("expected data preserved after round trip, got %v", m)
+	}
+}
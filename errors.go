@@ -0,0 +1,75 @@
+package gotoon
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Position 描述TOON源码中的一个位置，字段含义与 go/scanner.Position
+// 一致，用于精确定位Decode过程中遇到的问题。
+type Position struct {
+	Offset int // 从文档开头起的字节偏移
+	Line   int // 行号，从1开始
+	Column int // 列号，从1开始
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Error 是Decode过程中遇到的单个问题，风格参照 go/scanner.Error。
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList 聚合一次解析中遇到的所有问题，本身满足 error 接口。
+// Decode/DecodeJSON 在解析失败时返回的 error 底层就是 ErrorList，调用方
+// 可以用 errors.As 把它取回，从而一次性拿到全部问题及各自的位置，而不是
+// 只看到第一个。
+type ErrorList []*Error
+
+// Add 向列表追加一个错误。
+func (l *ErrorList) Add(pos Position, msg string) {
+	*l = append(*l, &Error{Pos: pos, Msg: msg})
+}
+
+func (l ErrorList) Len() int      { return len(l) }
+func (l ErrorList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l ErrorList) Less(i, j int) bool {
+	a, b := l[i].Pos, l[j].Pos
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	if a.Column != b.Column {
+		return a.Column < b.Column
+	}
+	return a.Offset < b.Offset
+}
+
+// Sort 按位置（行、列、偏移）对错误排序。
+func (l ErrorList) Sort() { sort.Sort(l) }
+
+// Error 实现 error 接口：展示第一个问题，以及其余问题的数量。
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err 在列表非空时把列表本身作为 error 返回，否则返回 nil，方便写成
+// `return value, errs.Err()`。
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
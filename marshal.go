@@ -0,0 +1,307 @@
+package gotoon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Marshaler 允许类型自定义自己的TOON编码方式，用法类似 encoding/json 的
+// json.Marshaler。
+type Marshaler interface {
+	MarshalTOON() ([]byte, error)
+}
+
+// Unmarshaler 允许类型自定义自己的TOON解码方式，用法类似 encoding/json 的
+// json.Unmarshaler。
+type Unmarshaler interface {
+	UnmarshalTOON([]byte) error
+}
+
+// Marshal 将 v 编码为TOON格式的字节切片。结构体字段通过 `toon:"..."`
+// 标签控制输出的键名，支持 omitempty（省略零值字段）和 tabular（强制
+// slice 字段以表格形式输出）选项，标签为 "-" 的字段会被跳过。
+func Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(Marshaler); ok {
+		return m.MarshalTOON()
+	}
+
+	s, err := Encode(v, DefaultOptions())
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+// Unmarshal 将TOON格式的 data 解码到 v 指向的变量。v 必须是非空指针，
+// 可以是结构体、map、slice 或基础类型；结构体字段按 `toon:"..."` 标签
+// （缺省则按字段名）匹配。
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("gotoon: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+
+	if u, ok := v.(Unmarshaler); ok {
+		return u.UnmarshalTOON(data)
+	}
+
+	value, err := Decode(string(data))
+	if err != nil {
+		return err
+	}
+
+	return assignValue(rv.Elem(), value)
+}
+
+// ------------------------------
+// 结构体字段元数据缓存
+// ------------------------------
+
+const tagName = "toon"
+
+// fieldInfo 描述结构体一个可编码字段的元数据，按 encoding/json 的
+// typeFields 缓存思路，每个类型只计算一次。
+type fieldInfo struct {
+	name      string
+	index     int
+	omitempty bool
+	tabular   bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]fieldInfo
+
+// cachedTypeFields 返回 t（必须是结构体类型）的可编码字段列表，结果会被
+// 缓存，避免每次编解码都重新反射分析 tag。
+func cachedTypeFields(t reflect.Type) []fieldInfo {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]fieldInfo)
+	}
+
+	var fields []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // 未导出字段
+		}
+
+		name, omitempty, tabular, skip := parseTag(sf.Tag.Get(tagName))
+		if skip {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, fieldInfo{
+			name:      name,
+			index:     i,
+			omitempty: omitempty,
+			tabular:   tabular,
+		})
+	}
+
+	cached, _ := fieldCache.LoadOrStore(t, fields)
+	return cached.([]fieldInfo)
+}
+
+// parseTag 解析形如 `field_name,omitempty,tabular` 的标签。name 为 "-"
+// 表示该字段应被跳过。
+func parseTag(tag string) (name string, omitempty, tabular, skip bool) {
+	if tag == "-" {
+		return "", false, false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			omitempty = true
+		case "tabular":
+			tabular = true
+		}
+	}
+	return name, omitempty, tabular, false
+}
+
+// isEmptyValue 判断一个反射值是否是其类型的零值，用于实现 omitempty。
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// structToMap 把一个结构体按其 toon 标签展开成 map[string]interface{}，
+// 供编码器里的 map/tabular 数组逻辑复用，遵从 omitempty。
+func structToMap(rv reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, f := range cachedTypeFields(rv.Type()) {
+		fv := rv.Field(f.index)
+		if f.omitempty && isEmptyValue(fv) {
+			continue
+		}
+		m[f.name] = fv.Interface()
+	}
+	return m
+}
+
+// ------------------------------
+// 解码结果到任意 Go 值
+// ------------------------------
+
+// assignValue 把 Decode 产生的通用值（map[string]interface{}、
+// []interface{}、string、int64、float64、bool 或 nil）赋给 rv。
+func assignValue(rv reflect.Value, data interface{}) error {
+	if rv.Kind() == reflect.Ptr {
+		if data == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return assignValue(rv.Elem(), data)
+	}
+
+	if data == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	if rv.Kind() == reflect.Interface {
+		rv.Set(reflect.ValueOf(data))
+		return nil
+	}
+
+	if rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(Unmarshaler); ok {
+			if s, isString := data.(string); isString {
+				return u.UnmarshalTOON([]byte(s))
+			}
+			enc, err := Encode(data, DefaultOptions())
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalTOON([]byte(enc))
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("gotoon: cannot decode %T into struct %s", data, rv.Type())
+		}
+		for _, f := range cachedTypeFields(rv.Type()) {
+			raw, ok := m[f.name]
+			if !ok {
+				continue
+			}
+			if err := assignValue(rv.Field(f.index), raw); err != nil {
+				return fmt.Errorf("gotoon: field %q: %w", f.name, err)
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		s, ok := data.([]interface{})
+		if !ok {
+			return fmt.Errorf("gotoon: cannot decode %T into %s", data, rv.Type())
+		}
+		out := reflect.MakeSlice(rv.Type(), len(s), len(s))
+		for i, item := range s {
+			if err := assignValue(out.Index(i), item); err != nil {
+				return err
+			}
+		}
+		rv.Set(out)
+		return nil
+
+	case reflect.Map:
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("gotoon: cannot decode %T into %s", data, rv.Type())
+		}
+		out := reflect.MakeMapWithSize(rv.Type(), len(m))
+		for k, item := range m {
+			val := reflect.New(rv.Type().Elem()).Elem()
+			if err := assignValue(val, item); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k), val)
+		}
+		rv.Set(out)
+		return nil
+
+	case reflect.String:
+		s, ok := data.(string)
+		if !ok {
+			return fmt.Errorf("gotoon: cannot decode %T into string", data)
+		}
+		rv.SetString(s)
+		return nil
+
+	case reflect.Bool:
+		b, ok := data.(bool)
+		if !ok {
+			return fmt.Errorf("gotoon: cannot decode %T into bool", data)
+		}
+		rv.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := data.(type) {
+		case int64:
+			rv.SetInt(n)
+		case float64:
+			rv.SetInt(int64(n))
+		default:
+			return fmt.Errorf("gotoon: cannot decode %T into %s", data, rv.Type())
+		}
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch n := data.(type) {
+		case int64:
+			rv.SetUint(uint64(n))
+		case float64:
+			rv.SetUint(uint64(n))
+		default:
+			return fmt.Errorf("gotoon: cannot decode %T into %s", data, rv.Type())
+		}
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		switch n := data.(type) {
+		case float64:
+			rv.SetFloat(n)
+		case int64:
+			rv.SetFloat(float64(n))
+		default:
+			return fmt.Errorf("gotoon: cannot decode %T into %s", data, rv.Type())
+		}
+		return nil
+
+	default:
+		v := reflect.ValueOf(data)
+		if !v.Type().AssignableTo(rv.Type()) {
+			return fmt.Errorf("gotoon: cannot decode %T into %s", data, rv.Type())
+		}
+		rv.Set(v)
+		return nil
+	}
+}
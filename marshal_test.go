@@ -0,0 +1,148 @@
+package gotoon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type person struct {
+	Name string `toon:"name"`
+	Nick string `toon:"nick,omitempty"`
+	Age  int    `toon:"age"`
+}
+
+func TestMarshalUnmarshalOmitempty(t *testing.T) {
+	in := person{Name: "Alice", Age: 30}
+
+	out, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(out), "nick") {
+		t.Errorf("expected omitempty field to be dropped, got:\n%s", out)
+	}
+
+	var got person
+	if err := Unmarshal(out, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got != in {
+		t.Errorf("expected %+v after round trip, got %+v", in, got)
+	}
+
+	in.Nick = "Ally"
+	out, err = Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(out), `nick: "Ally"`) {
+		t.Errorf("expected nick field when non-empty, got:\n%s", out)
+	}
+}
+
+type event struct {
+	Items []item `toon:"items,tabular"`
+}
+
+type item struct {
+	ID   int    `toon:"id,omitempty"`
+	Name string `toon:"name,omitempty"`
+}
+
+func TestMarshalTabularTagForcesTableWithUnionKeys(t *testing.T) {
+	// Rows have no field in common (each omits the other's field), so
+	// getCommonKeys returns nothing and the header must fall back to
+	// getUnionKeys to cover both.
+	in := event{Items: []item{
+		{ID: 1},
+		{Name: "Bob"},
+	}}
+
+	out, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := "{id, name}:"
+	if !strings.Contains(string(out), want) {
+		t.Errorf("expected forced tabular header %q (union of fields across rows), got:\n%s", want, out)
+	}
+}
+
+type customPoint struct {
+	X, Y int
+}
+
+func (p customPoint) MarshalTOON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d", p.X, p.Y)), nil
+}
+
+func (p *customPoint) UnmarshalTOON(data []byte) error {
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("customPoint: malformed value %q", data)
+	}
+	var err error
+	if p.X, err = strconv.Atoi(parts[0]); err != nil {
+		return err
+	}
+	if p.Y, err = strconv.Atoi(parts[1]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestMarshalUnmarshalerHooks(t *testing.T) {
+	in := customPoint{X: 3, Y: 4}
+
+	out, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(out) != "3:4" {
+		t.Errorf("expected MarshalTOON output \"3:4\", got %q", out)
+	}
+
+	var got customPoint
+	if err := Unmarshal(out, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got != in {
+		t.Errorf("expected %+v after round trip, got %+v", in, got)
+	}
+}
+
+func TestMarshalUnmarshalerHooksInSlice(t *testing.T) {
+	// Marshaler must still be invoked when customPoint appears as a slice
+	// element, not just at the top level.
+	in := []customPoint{{X: 1, Y: 2}, {X: 3, Y: 4}}
+
+	out, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(out), "\"X\"") || strings.Contains(string(out), "x:") {
+		t.Errorf("expected MarshalTOON output, got reflected struct dump:\n%s", out)
+	}
+	if !strings.Contains(string(out), "1:2") || !strings.Contains(string(out), "3:4") {
+		t.Errorf("expected each element's MarshalTOON output, got:\n%s", out)
+	}
+}
+
+type shape struct {
+	Origin customPoint `toon:"origin"`
+}
+
+func TestUnmarshalerHookOnStructField(t *testing.T) {
+	// UnmarshalTOON must be invoked for a struct field too, not just the
+	// outermost argument to Unmarshal.
+	var got shape
+	if err := Unmarshal([]byte(`{origin: "5:6"}`), &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.Origin != (customPoint{X: 5, Y: 6}) {
+		t.Errorf("expected Origin {5 6}, got %+v", got.Origin)
+	}
+}
@@ -0,0 +1,1248 @@
+// Package query 实现了一个精简的JSONPath查询引擎，运行在 gotoon.Decode
+// 产出的 interface{} 树上：map[string]interface{}、[]interface{} 以及
+// string/int64/float64/bool/nil 标量。
+//
+// 支持的语法子集：根 `$`、子节点 `.name` / `['name']`、递归下降 `..`、
+// 通配符 `*`、数组下标/切片 `[n]` / `[a:b:c]`、并集 `[a,b]`，以及过滤
+// 表达式 `[?(<expr>)]`（支持 ==、!=、<、<=、>、>=、&&、||、!，以及
+// `@.field` 当前节点引用和字符串/数字/布尔/null字面量）。
+package query
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xxjwxc/gotoon"
+)
+
+// Path 是编译后的、可重复求值的JSONPath表达式。
+type Path struct {
+	raw      string
+	segments []segment
+}
+
+// Query 编译 path 并对 root 求值，返回所有匹配的节点。
+func Query(root interface{}, path string) ([]interface{}, error) {
+	p, err := Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Find(root)
+}
+
+// Compile 把一个JSONPath字符串编译为可重用的 *Path。
+func Compile(path string) (*Path, error) {
+	p := &pathParser{input: path}
+	segments, err := p.parsePath()
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	return &Path{raw: path, segments: segments}, nil
+}
+
+// String 返回原始路径表达式。
+func (p *Path) String() string { return p.raw }
+
+// Find 对 root 求值该路径，返回所有匹配的节点。
+func (p *Path) Find(root interface{}) ([]interface{}, error) {
+	nodes := []interface{}{root}
+	for _, seg := range p.segments {
+		nodes = seg.apply(nodes)
+	}
+	return nodes, nil
+}
+
+// Result 是 QueryStream 在channel上产出的一个匹配项或错误。
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// QueryStream 编译 path，并在一个goroutine里不断从 dec 读取后续文档，
+// 随着 Decoder 消费输入持续把匹配结果送入返回的channel，直到流结束或出错
+// 为止（channel随之关闭）。注意：每个文档仍然是被 Decoder 整体解析之后
+// 才参与匹配，真正的效果是“无需把多文档流整体留在内存里”，而不是单个
+// 超大文档内部的逐行流式查询——后者见 QueryArrayStream。
+func QueryStream(dec *gotoon.Decoder, path string) (<-chan Result, error) {
+	p, err := Compile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Result)
+	go func() {
+		defer close(ch)
+		for {
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				if err != io.EOF {
+					ch <- Result{Err: err}
+				}
+				return
+			}
+
+			matches, err := p.Find(v)
+			if err != nil {
+				ch <- Result{Err: err}
+				return
+			}
+			for _, m := range matches {
+				ch <- Result{Value: m}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// QueryArrayStream 对 path 指向的数组做真正意义上的单文档流式读取：它直接
+// 消费 dec.Token() 产出的原始标记，每次只具体化数组的一个元素（或一行表格
+// 数据）就送入返回的channel，不会像 Query/QueryStream 那样先把整个文档
+// 解码成 interface{} 再匹配，所以才真正适合“从一个超大TOON文档里提取某个
+// 字段、不把它整体留在内存里”的场景。
+//
+// 代价是 path 只能是一串纯子节点访问（例如 "$.a.b.items"），最终必须落在
+// 一个数组上；通配符、递归下降、切片、并集和过滤表达式都不受支持，需要
+// 这些能力时请改用 Query 或 QueryStream。path 上经过但未匹配的兄弟字段
+// 仍会被整体具体化后丢弃——这些字段本身的大小通常远小于目标数组，真正
+// 被流式处理、不整体驻留内存的是 path 指向的那个数组。
+func QueryArrayStream(dec *gotoon.Decoder, path string) (<-chan Result, error) {
+	p, err := Compile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(p.segments))
+	for i, seg := range p.segments {
+		if seg.kind != segChild {
+			return nil, fmt.Errorf("query: QueryArrayStream only supports plain child paths (no wildcards/recursion/slices/filters), got unsupported segment in %q", path)
+		}
+		names[i] = seg.name
+	}
+
+	ch := make(chan Result)
+	go func() {
+		defer close(ch)
+		if err := streamArrayAt(dec, names, ch); err != nil && err != io.EOF {
+			ch <- Result{Err: err}
+		}
+	}()
+
+	return ch, nil
+}
+
+// streamArrayAt 沿着 names 描述的字段链逐级下降，定位到目标数组后交给
+// streamArrayElements 逐元素流式产出。
+func streamArrayAt(dec *gotoon.Decoder, names []string, ch chan<- Result) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if tok.Kind != gotoon.TokenObjectStart {
+			return fmt.Errorf("query: expected object while descending to %q, got token kind %d", name, tok.Kind)
+		}
+		next, found, err := descendToField(dec, name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("query: field %q not found", name)
+		}
+		tok = next
+	}
+
+	return streamArrayElements(dec, tok, ch)
+}
+
+// descendToField 消费当前对象（紧跟在已读出的 TokenObjectStart 之后）的
+// 字段，一边丢弃不匹配的兄弟字段的值，一边寻找 name；找到后返回该字段
+// 值的起始 token，调用方可以继续下降或开始流式读取数组。
+func descendToField(dec *gotoon.Decoder, name string) (gotoon.Token, bool, error) {
+	keyTok, err := skipSeparators(dec, gotoon.Token{Kind: gotoon.TokenRowEnd})
+	if err != nil {
+		return gotoon.Token{}, false, err
+	}
+
+	for keyTok.Kind != gotoon.TokenObjectEnd {
+		if keyTok.Kind != gotoon.TokenScalar {
+			return gotoon.Token{}, false, fmt.Errorf("query: expected object key, got token kind %d", keyTok.Kind)
+		}
+		key := unquoteScalar(keyTok.Value)
+
+		colonTok, err := dec.Token()
+		if err != nil {
+			return gotoon.Token{}, false, err
+		}
+		if colonTok.Kind != gotoon.TokenScalar || colonTok.Value != ":" {
+			return gotoon.Token{}, false, fmt.Errorf("query: expected ':' after key %q", key)
+		}
+
+		valTok, err := dec.Token()
+		if err != nil {
+			return gotoon.Token{}, false, err
+		}
+
+		if key == name {
+			return valTok, true, nil
+		}
+
+		_, after, err := materializeValue(dec, valTok)
+		if err != nil {
+			return gotoon.Token{}, false, err
+		}
+		keyTok, err = skipSeparators(dec, after)
+		if err != nil {
+			return gotoon.Token{}, false, err
+		}
+	}
+
+	return gotoon.Token{}, false, nil
+}
+
+// skipSeparators 从 from 开始，跳过 TokenRow（逗号）和 TokenRowEnd（跨越的
+// 换行），直到遇到第一个真正有结构意义的 token——这两种 token 在对象字段、
+// 数组元素之间只充当分隔符，不携带别的信息。传入 {Kind: TokenRowEnd} 作为
+// from 可以用来读取“下一个有意义的 token”，而不先验地假设已经有一个候选。
+func skipSeparators(dec *gotoon.Decoder, from gotoon.Token) (gotoon.Token, error) {
+	tok := from
+	for tok.Kind == gotoon.TokenRow || tok.Kind == gotoon.TokenRowEnd {
+		var err error
+		tok, err = dec.Token()
+		if err != nil {
+			return gotoon.Token{}, err
+		}
+	}
+	return tok, nil
+}
+
+// streamArrayElements 把 valueTok（必须是 TokenArrayStart）描述的数组逐
+// 元素（普通数组）或逐行（表格数组）具体化并送入 ch，每次只在内存里持有
+// 一个元素/一行。
+func streamArrayElements(dec *gotoon.Decoder, valueTok gotoon.Token, ch chan<- Result) error {
+	if valueTok.Kind != gotoon.TokenArrayStart {
+		return fmt.Errorf("query: path does not point at an array (got token kind %d)", valueTok.Kind)
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok.Kind == gotoon.TokenTabularHeader {
+		keys := parseHeaderKeys(tok.Value)
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		for tok.Kind != gotoon.TokenArrayEnd {
+			if tok.Kind == gotoon.TokenRowEnd {
+				if tok, err = dec.Token(); err != nil {
+					return err
+				}
+				continue
+			}
+			row := make(map[string]interface{}, len(keys))
+			for i, k := range keys {
+				v, err := unquoteScalarValue(tok.Value)
+				if err != nil {
+					return err
+				}
+				row[k] = v
+				if tok, err = dec.Token(); err != nil {
+					return err
+				}
+				if i < len(keys)-1 && tok.Kind == gotoon.TokenRow {
+					if tok, err = dec.Token(); err != nil {
+						return err
+					}
+				}
+			}
+			ch <- Result{Value: row}
+		}
+		return nil
+	}
+
+	for tok.Kind != gotoon.TokenArrayEnd {
+		if tok.Kind == gotoon.TokenRow || tok.Kind == gotoon.TokenRowEnd {
+			if tok, err = dec.Token(); err != nil {
+				return err
+			}
+			continue
+		}
+		val, after, err := materializeValue(dec, tok)
+		if err != nil {
+			return err
+		}
+		ch <- Result{Value: val}
+		tok = after
+	}
+	return nil
+}
+
+// materializeValue 从 tok（某个值的起始 token）开始，把它对应的值完整
+// 具体化为 Query/Find 通常处理的 interface{} 形态，并返回紧跟在这个值
+// 之后的下一个 token，供调用方继续遍历。用于 QueryArrayStream 里跳过
+// 不相关字段，以及具体化数组里复合类型的元素。
+func materializeValue(dec *gotoon.Decoder, tok gotoon.Token) (interface{}, gotoon.Token, error) {
+	switch tok.Kind {
+	case gotoon.TokenObjectStart:
+		m := make(map[string]interface{})
+		next, err := skipSeparators(dec, gotoon.Token{Kind: gotoon.TokenRowEnd})
+		if err != nil {
+			return nil, gotoon.Token{}, err
+		}
+		for next.Kind != gotoon.TokenObjectEnd {
+			if next.Kind != gotoon.TokenScalar {
+				return nil, gotoon.Token{}, fmt.Errorf("query: expected object key, got token kind %d", next.Kind)
+			}
+			key := unquoteScalar(next.Value)
+
+			colon, err := dec.Token()
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+			if colon.Kind != gotoon.TokenScalar || colon.Value != ":" {
+				return nil, gotoon.Token{}, fmt.Errorf("query: expected ':' after key %q", key)
+			}
+
+			valTok, err := dec.Token()
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+			val, after, err := materializeValue(dec, valTok)
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+			m[key] = val
+			next, err = skipSeparators(dec, after)
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+		}
+		final, err := dec.Token()
+		if err != nil {
+			return nil, gotoon.Token{}, err
+		}
+		return m, final, nil
+
+	case gotoon.TokenArrayStart:
+		first, err := dec.Token()
+		if err != nil {
+			return nil, gotoon.Token{}, err
+		}
+
+		if first.Kind == gotoon.TokenTabularHeader {
+			keys := parseHeaderKeys(first.Value)
+			var rows []interface{}
+			cur, err := dec.Token()
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+			for cur.Kind != gotoon.TokenArrayEnd {
+				if cur.Kind == gotoon.TokenRowEnd {
+					if cur, err = dec.Token(); err != nil {
+						return nil, gotoon.Token{}, err
+					}
+					continue
+				}
+				row := make(map[string]interface{}, len(keys))
+				for i, k := range keys {
+					v, err := unquoteScalarValue(cur.Value)
+					if err != nil {
+						return nil, gotoon.Token{}, err
+					}
+					row[k] = v
+					if cur, err = dec.Token(); err != nil {
+						return nil, gotoon.Token{}, err
+					}
+					if i < len(keys)-1 && cur.Kind == gotoon.TokenRow {
+						if cur, err = dec.Token(); err != nil {
+							return nil, gotoon.Token{}, err
+						}
+					}
+				}
+				rows = append(rows, row)
+			}
+			final, err := dec.Token()
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+			return rows, final, nil
+		}
+
+		var elems []interface{}
+		cur := first
+		for cur.Kind != gotoon.TokenArrayEnd {
+			if cur.Kind == gotoon.TokenRow || cur.Kind == gotoon.TokenRowEnd {
+				if cur, err = dec.Token(); err != nil {
+					return nil, gotoon.Token{}, err
+				}
+				continue
+			}
+			val, after, err := materializeValue(dec, cur)
+			if err != nil {
+				return nil, gotoon.Token{}, err
+			}
+			elems = append(elems, val)
+			cur = after
+		}
+		final, err := dec.Token()
+		if err != nil {
+			return nil, gotoon.Token{}, err
+		}
+		return elems, final, nil
+
+	case gotoon.TokenScalar:
+		v, err := unquoteScalarValue(tok.Value)
+		if err != nil {
+			return nil, gotoon.Token{}, err
+		}
+		next, err := dec.Token()
+		if err != nil {
+			return nil, gotoon.Token{}, err
+		}
+		return v, next, nil
+
+	default:
+		return nil, gotoon.Token{}, fmt.Errorf("query: unexpected token kind %d", tok.Kind)
+	}
+}
+
+// unquoteScalar 把一个对象键 token 的原始文本还原为字符串：带引号的去掉
+// 引号和转义，裸标识符原样返回。
+func unquoteScalar(raw string) string {
+	if len(raw) > 0 && raw[0] == '"' {
+		if s, err := strconv.Unquote(raw); err == nil {
+			return s
+		}
+	}
+	return raw
+}
+
+// unquoteScalarValue 把一个标量 token 的原始文本解析为 Query/Find 通常
+// 处理的 Go 值：true/false/null 关键字、带引号的字符串、数字，其余原样
+// 当作字符串返回（与 ast.ParseAST 对裸标识符标量的处理方式一致）。
+func unquoteScalarValue(raw string) (interface{}, error) {
+	switch raw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if len(raw) > 0 && raw[0] == '"' {
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("query: invalid string literal %q: %w", raw, err)
+		}
+		return s, nil
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	return raw, nil
+}
+
+// parseHeaderKeys 把 Decoder.Token 为表格头重建出的 "{k1, k2}:" 文本还原
+// 成键名列表。
+func parseHeaderKeys(header string) []string {
+	s := strings.TrimSuffix(header, ":")
+	s = strings.TrimPrefix(s, "{")
+	s = strings.TrimSuffix(s, "}")
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	keys := make([]string, len(parts))
+	for i, part := range parts {
+		keys[i] = strings.TrimSpace(part)
+	}
+	return keys
+}
+
+// ------------------------------
+// path segments
+// ------------------------------
+
+type segmentKind int
+
+const (
+	segChild segmentKind = iota
+	segWildcard
+	segRecursive
+	segIndex
+	segSlice
+	segUnion
+	segFilter
+)
+
+type sliceSpec struct {
+	start, end, step *int
+}
+
+type segment struct {
+	kind   segmentKind
+	name   string // segChild, segRecursive ("*" 表示递归通配)
+	index  int    // segIndex
+	slice  sliceSpec
+	union  []interface{} // segUnion: string(键名) 或 int(下标)
+	filter *filterExpr   // segFilter
+}
+
+func (s segment) apply(nodes []interface{}) []interface{} {
+	var out []interface{}
+	for _, n := range nodes {
+		switch s.kind {
+		case segChild:
+			if m, ok := n.(map[string]interface{}); ok {
+				if v, exists := m[s.name]; exists {
+					out = append(out, v)
+				}
+			}
+		case segWildcard:
+			out = append(out, children(n)...)
+		case segRecursive:
+			out = append(out, collectRecursive(n, s.name)...)
+		case segIndex:
+			if arr, ok := n.([]interface{}); ok {
+				idx := s.index
+				if idx < 0 {
+					idx += len(arr)
+				}
+				if idx >= 0 && idx < len(arr) {
+					out = append(out, arr[idx])
+				}
+			}
+		case segSlice:
+			if arr, ok := n.([]interface{}); ok {
+				out = append(out, sliceArray(arr, s.slice)...)
+			}
+		case segUnion:
+			out = append(out, applyUnion(n, s.union)...)
+		case segFilter:
+			for _, c := range children(n) {
+				if evalFilter(s.filter, c) {
+					out = append(out, c)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// children 按确定性顺序（map按键排序）返回 n 的直接子节点。
+func children(n interface{}) []interface{} {
+	switch v := n.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, 0, len(v))
+		for _, k := range keys {
+			out = append(out, v[k])
+		}
+		return out
+	case []interface{}:
+		return v
+	default:
+		return nil
+	}
+}
+
+func applyUnion(n interface{}, union []interface{}) []interface{} {
+	var out []interface{}
+	switch v := n.(type) {
+	case []interface{}:
+		for _, u := range union {
+			idx, ok := u.(int)
+			if !ok {
+				continue
+			}
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx >= 0 && idx < len(v) {
+				out = append(out, v[idx])
+			}
+		}
+	case map[string]interface{}:
+		for _, u := range union {
+			name, ok := u.(string)
+			if !ok {
+				continue
+			}
+			if val, exists := v[name]; exists {
+				out = append(out, val)
+			}
+		}
+	}
+	return out
+}
+
+func collectRecursive(n interface{}, name string) []interface{} {
+	var out []interface{}
+	var walk func(x interface{})
+	walk = func(x interface{}) {
+		switch v := x.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				child := v[k]
+				if name == "*" || k == name {
+					out = append(out, child)
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				if name == "*" {
+					out = append(out, child)
+				}
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	return out
+}
+
+func sliceArray(arr []interface{}, spec sliceSpec) []interface{} {
+	n := len(arr)
+	start, end, step := 0, n, 1
+
+	if spec.step != nil {
+		step = *spec.step
+	}
+	if step == 0 {
+		step = 1
+	}
+	if spec.start != nil {
+		start = *spec.start
+		if start < 0 {
+			start += n
+		}
+	} else if step < 0 {
+		start = n - 1
+	}
+	if spec.end != nil {
+		end = *spec.end
+		if end < 0 {
+			end += n
+		}
+	} else if step < 0 {
+		end = -1
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if start > n {
+		start = n
+	}
+	if end > n {
+		end = n
+	}
+
+	var out []interface{}
+	if step > 0 {
+		for i := start; i < end; i += step {
+			out = append(out, arr[i])
+		}
+	} else {
+		if end < -1 {
+			end = -1
+		}
+		for i := start; i > end; i += step {
+			if i < 0 || i >= n {
+				continue
+			}
+			out = append(out, arr[i])
+		}
+	}
+	return out
+}
+
+// ------------------------------
+// filter expressions: [?(<expr>)]
+// ------------------------------
+
+type filterExprKind int
+
+const (
+	filterOr filterExprKind = iota
+	filterAnd
+	filterNot
+	filterCmp
+	filterTruthy
+)
+
+type operand struct {
+	isCurrent bool
+	path      []string
+	literal   interface{}
+}
+
+type filterExpr struct {
+	kind     filterExprKind
+	children []*filterExpr
+	op       string
+	left     *operand
+	right    *operand
+}
+
+func evalFilter(e *filterExpr, candidate interface{}) bool {
+	switch e.kind {
+	case filterOr:
+		for _, c := range e.children {
+			if evalFilter(c, candidate) {
+				return true
+			}
+		}
+		return false
+	case filterAnd:
+		for _, c := range e.children {
+			if !evalFilter(c, candidate) {
+				return false
+			}
+		}
+		return true
+	case filterNot:
+		return !evalFilter(e.children[0], candidate)
+	case filterTruthy:
+		return truthy(resolveOperand(e.left, candidate))
+	case filterCmp:
+		lv := resolveOperand(e.left, candidate)
+		rv := resolveOperand(e.right, candidate)
+		return compareValues(lv, rv, e.op)
+	}
+	return false
+}
+
+func resolveOperand(o *operand, candidate interface{}) interface{} {
+	if !o.isCurrent {
+		return o.literal
+	}
+
+	var cur interface{} = candidate
+	for _, field := range o.path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[field]
+	}
+	return cur
+}
+
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case float64:
+		return val != 0
+	case int64:
+		return val != 0
+	default:
+		return true
+	}
+}
+
+func compareValues(lv, rv interface{}, op string) bool {
+	if op == "==" || op == "!=" {
+		eq := valuesEqual(lv, rv)
+		if op == "!=" {
+			return !eq
+		}
+		return eq
+	}
+
+	lf, lok := toFloat(lv)
+	rf, rok := toFloat(rv)
+	if lok && rok {
+		switch op {
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		}
+	}
+
+	ls, lok := lv.(string)
+	rs, rok := rv.(string)
+	if lok && rok {
+		switch op {
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		}
+	}
+
+	return false
+}
+
+func valuesEqual(lv, rv interface{}) bool {
+	lf, lok := toFloat(lv)
+	rf, rok := toFloat(rv)
+	if lok && rok {
+		return lf == rf
+	}
+	return lv == rv
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// ------------------------------
+// 解析器
+// ------------------------------
+
+type pathParser struct {
+	input string
+	pos   int
+}
+
+func (p *pathParser) parsePath() ([]segment, error) {
+	if !strings.HasPrefix(p.input, "$") {
+		return nil, fmt.Errorf("path must start with '$', got %q", p.input)
+	}
+	p.pos = 1
+
+	var segments []segment
+	for p.pos < len(p.input) {
+		seg, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+func (p *pathParser) cur() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *pathParser) parseSegment() (segment, error) {
+	switch p.cur() {
+	case '.':
+		p.pos++
+		if p.cur() == '.' {
+			p.pos++
+			if p.cur() == '*' {
+				p.pos++
+				return segment{kind: segRecursive, name: "*"}, nil
+			}
+			name := p.parseIdent()
+			if name == "" {
+				return segment{}, fmt.Errorf("expected name after '..' at %d", p.pos)
+			}
+			return segment{kind: segRecursive, name: name}, nil
+		}
+		if p.cur() == '*' {
+			p.pos++
+			return segment{kind: segWildcard}, nil
+		}
+		name := p.parseIdent()
+		if name == "" {
+			return segment{}, fmt.Errorf("expected field name at %d", p.pos)
+		}
+		return segment{kind: segChild, name: name}, nil
+
+	case '[':
+		p.pos++
+		seg, err := p.parseBracket()
+		if err != nil {
+			return segment{}, err
+		}
+		if p.cur() != ']' {
+			return segment{}, fmt.Errorf("expected ']' at %d", p.pos)
+		}
+		p.pos++
+		return seg, nil
+
+	default:
+		return segment{}, fmt.Errorf("unexpected character %q at %d", p.cur(), p.pos)
+	}
+}
+
+func (p *pathParser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.input) && isIdentRune(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func isIdentRune(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *pathParser) parseBracket() (segment, error) {
+	p.skipSpace()
+
+	if p.cur() == '*' {
+		p.pos++
+		p.skipSpace()
+		return segment{kind: segWildcard}, nil
+	}
+
+	if p.cur() == '?' {
+		p.pos++
+		p.skipSpace()
+		if p.cur() != '(' {
+			return segment{}, fmt.Errorf("expected '(' after '?' at %d", p.pos)
+		}
+		p.pos++
+		expr, err := p.parseExpr()
+		if err != nil {
+			return segment{}, err
+		}
+		p.skipSpace()
+		if p.cur() != ')' {
+			return segment{}, fmt.Errorf("expected ')' at %d", p.pos)
+		}
+		p.pos++
+		p.skipSpace()
+		return segment{kind: segFilter, filter: expr}, nil
+	}
+
+	if p.cur() == '\'' || p.cur() == '"' {
+		var names []interface{}
+		for {
+			name, err := p.parseQuoted()
+			if err != nil {
+				return segment{}, err
+			}
+			names = append(names, name)
+			p.skipSpace()
+			if p.cur() == ',' {
+				p.pos++
+				p.skipSpace()
+				continue
+			}
+			break
+		}
+		if len(names) == 1 {
+			return segment{kind: segChild, name: names[0].(string)}, nil
+		}
+		return segment{kind: segUnion, union: names}, nil
+	}
+
+	// numeric index / slice / union of indices
+	var parts []*int
+	colons := 0
+	var union []interface{}
+	for {
+		p.skipSpace()
+		var n *int
+		if p.cur() == '-' || (p.cur() >= '0' && p.cur() <= '9') {
+			v, err := p.parseInt()
+			if err != nil {
+				return segment{}, err
+			}
+			n = &v
+		}
+		parts = append(parts, n)
+		p.skipSpace()
+		if p.cur() == ':' {
+			colons++
+			p.pos++
+			continue
+		}
+		if p.cur() == ',' {
+			if n == nil {
+				return segment{}, fmt.Errorf("expected index before ',' at %d", p.pos)
+			}
+			union = append(union, *n)
+			p.pos++
+			continue
+		}
+		break
+	}
+
+	if colons > 0 {
+		spec := sliceSpec{}
+		if len(parts) > 0 {
+			spec.start = parts[0]
+		}
+		if len(parts) > 1 {
+			spec.end = parts[1]
+		}
+		if len(parts) > 2 {
+			spec.step = parts[2]
+		}
+		return segment{kind: segSlice, slice: spec}, nil
+	}
+
+	if len(union) > 0 {
+		if parts[len(parts)-1] == nil {
+			return segment{}, fmt.Errorf("expected index at %d", p.pos)
+		}
+		union = append(union, *parts[len(parts)-1])
+		return segment{kind: segUnion, union: union}, nil
+	}
+
+	if len(parts) != 1 || parts[0] == nil {
+		return segment{}, fmt.Errorf("invalid index expression at %d", p.pos)
+	}
+	return segment{kind: segIndex, index: *parts[0]}, nil
+}
+
+func (p *pathParser) parseInt() (int, error) {
+	start := p.pos
+	if p.cur() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	return strconv.Atoi(p.input[start:p.pos])
+}
+
+func (p *pathParser) parseQuoted() (string, error) {
+	quote := p.cur()
+	if quote != '\'' && quote != '"' {
+		return "", fmt.Errorf("expected quote at %d", p.pos)
+	}
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != quote {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return "", fmt.Errorf("unterminated string starting at %d", start)
+	}
+	s := p.input[start:p.pos]
+	p.pos++ // closing quote
+	return s, nil
+}
+
+// ------------------------------
+// 过滤表达式解析: Expr := Or; Or := And ('||' And)*; And := Unary ('&&' Unary)*
+// Unary := '!' Unary | Cmp; Cmp := '(' Expr ')' | Operand (op Operand)?
+// ------------------------------
+
+func (p *pathParser) parseExpr() (*filterExpr, error) {
+	return p.parseOr()
+}
+
+func (p *pathParser) parseOr() (*filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []*filterExpr{left}
+	for {
+		p.skipSpace()
+		if strings.HasPrefix(p.input[p.pos:], "||") {
+			p.pos += 2
+			right, err := p.parseAnd()
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, right)
+			continue
+		}
+		break
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return &filterExpr{kind: filterOr, children: terms}, nil
+}
+
+func (p *pathParser) parseAnd() (*filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := []*filterExpr{left}
+	for {
+		p.skipSpace()
+		if strings.HasPrefix(p.input[p.pos:], "&&") {
+			p.pos += 2
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, right)
+			continue
+		}
+		break
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return &filterExpr{kind: filterAnd, children: terms}, nil
+}
+
+func (p *pathParser) parseUnary() (*filterExpr, error) {
+	p.skipSpace()
+	if p.cur() == '!' {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &filterExpr{kind: filterNot, children: []*filterExpr{inner}}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *pathParser) parseCmp() (*filterExpr, error) {
+	p.skipSpace()
+	if p.cur() == '(' {
+		p.pos++
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.cur() != ')' {
+			return nil, fmt.Errorf("expected ')' at %d", p.pos)
+		}
+		p.pos++
+		return e, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	op := p.peekOp()
+	if op == "" {
+		return &filterExpr{kind: filterTruthy, left: left}, nil
+	}
+	p.pos += len(op)
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &filterExpr{kind: filterCmp, op: op, left: left, right: right}, nil
+}
+
+func (p *pathParser) peekOp() string {
+	rest := p.input[p.pos:]
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *pathParser) parseOperand() (*operand, error) {
+	p.skipSpace()
+	switch {
+	case p.cur() == '@':
+		p.pos++
+		var path []string
+		for p.cur() == '.' || p.cur() == '[' {
+			if p.cur() == '.' {
+				p.pos++
+				name := p.parseIdent()
+				if name == "" {
+					return nil, fmt.Errorf("expected field name after '@.' at %d", p.pos)
+				}
+				path = append(path, name)
+			} else {
+				p.pos++
+				name, err := p.parseQuoted()
+				if err != nil {
+					return nil, err
+				}
+				path = append(path, name)
+				if p.cur() != ']' {
+					return nil, fmt.Errorf("expected ']' at %d", p.pos)
+				}
+				p.pos++
+			}
+		}
+		return &operand{isCurrent: true, path: path}, nil
+
+	case p.cur() == '\'' || p.cur() == '"':
+		s, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return &operand{literal: s}, nil
+
+	case strings.HasPrefix(p.input[p.pos:], "true"):
+		p.pos += 4
+		return &operand{literal: true}, nil
+
+	case strings.HasPrefix(p.input[p.pos:], "false"):
+		p.pos += 5
+		return &operand{literal: false}, nil
+
+	case strings.HasPrefix(p.input[p.pos:], "null"):
+		p.pos += 4
+		return &operand{literal: nil}, nil
+
+	case p.cur() == '-' || (p.cur() >= '0' && p.cur() <= '9'):
+		start := p.pos
+		if p.cur() == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at %d: %w", start, err)
+		}
+		return &operand{literal: f}, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected operand at %d", p.pos)
+	}
+}
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xxjwxc/gotoon"
+)
+
+func sampleDoc() interface{} {
+	return map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"id": int64(1), "name": "Alice", "role": "admin"},
+			map[string]interface{}{"id": int64(2), "name": "Bob", "role": "user"},
+			map[string]interface{}{"id": int64(3), "name": "Carol", "role": "admin"},
+		},
+	}
+}
+
+func TestQueryChildAndWildcard(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected []interface{}
+	}{
+		{
+			name:     "child name",
+			path:     "$.users[?(@.role==\"admin\")].name",
+			expected: []interface{}{"Alice", "Carol"},
+		},
+		{
+			name:     "wildcard",
+			path:     "$.users[*].name",
+			expected: []interface{}{"Alice", "Bob", "Carol"},
+		},
+		{
+			name:     "index",
+			path:     "$.users[0].name",
+			expected: []interface{}{"Alice"},
+		},
+		{
+			name:     "slice",
+			path:     "$.users[0:2].name",
+			expected: []interface{}{"Alice", "Bob"},
+		},
+		{
+			name:     "recursive descent",
+			path:     "$..id",
+			expected: []interface{}{int64(1), int64(2), int64(3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Query(sampleDoc(), tt.path)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQueryFilterOperators(t *testing.T) {
+	doc := sampleDoc()
+
+	got, err := Query(doc, "$.users[?(@.id>1 && @.id<3)].name")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	expected := []interface{}{"Bob"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestCompileInvalidPath(t *testing.T) {
+	if _, err := Compile("users.name"); err == nil {
+		t.Fatal("expected error for path missing leading '$'")
+	}
+}
+
+func drainArrayStream(t *testing.T, ch <-chan Result) []interface{} {
+	t.Helper()
+	var got []interface{}
+	for r := range ch {
+		if r.Err != nil {
+			t.Fatalf("unexpected error on stream: %v", r.Err)
+		}
+		got = append(got, r.Value)
+	}
+	return got
+}
+
+func TestQueryArrayStreamTabular(t *testing.T) {
+	input := `{ meta: "v1", users: [ 2 {id, name}:
+  1, "Alice"
+  2, "Bob"
+] }`
+	dec := gotoon.NewDecoder(strings.NewReader(input))
+
+	ch, err := QueryArrayStream(dec, "$.users")
+	if err != nil {
+		t.Fatalf("QueryArrayStream failed: %v", err)
+	}
+
+	got := drainArrayStream(t, ch)
+	want := []interface{}{
+		map[string]interface{}{"id": int64(1), "name": "Alice"},
+		map[string]interface{}{"id": int64(2), "name": "Bob"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestQueryArrayStreamPlain(t *testing.T) {
+	dec := gotoon.NewDecoder(strings.NewReader(`{a: [1, "x", {b: 2}]}`))
+
+	ch, err := QueryArrayStream(dec, "$.a")
+	if err != nil {
+		t.Fatalf("QueryArrayStream failed: %v", err)
+	}
+
+	got := drainArrayStream(t, ch)
+	want := []interface{}{
+		int64(1),
+		"x",
+		map[string]interface{}{"b": int64(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestQueryArrayStreamNestedPath(t *testing.T) {
+	dec := gotoon.NewDecoder(strings.NewReader(`{a: {b: {items: [1, 2, 3]}}}`))
+
+	ch, err := QueryArrayStream(dec, "$.a.b.items")
+	if err != nil {
+		t.Fatalf("QueryArrayStream failed: %v", err)
+	}
+
+	got := drainArrayStream(t, ch)
+	want := []interface{}{int64(1), int64(2), int64(3)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestQueryArrayStreamRejectsUnsupportedSegments(t *testing.T) {
+	dec := gotoon.NewDecoder(strings.NewReader(`{a: [1, 2]}`))
+
+	if _, err := QueryArrayStream(dec, "$.*"); err == nil {
+		t.Fatal("expected error for wildcard segment")
+	}
+}
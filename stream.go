@@ -0,0 +1,226 @@
+package gotoon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Encoder 将TOON值顺序写入一个 io.Writer，用法与 encoding/json 的 Encoder
+// 类似：每次 Encode 调用写入一个值，调用方可以在同一个流里连续写入多个文档。
+type Encoder struct {
+	w       io.Writer
+	options Options
+}
+
+// NewEncoder 返回一个写入 w 的 Encoder，默认使用 DefaultOptions。
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, options: DefaultOptions()}
+}
+
+// SetOptions 设置后续 Encode 调用使用的编码选项。
+func (e *Encoder) SetOptions(options Options) {
+	e.options = options
+}
+
+// Encode 将 v 编码为一个TOON值并写入底层的 io.Writer，随后追加一个换行符
+// 作为文档分隔，方便 Decoder 增量读取多文档流。
+func (e *Encoder) Encode(v interface{}) error {
+	s, err := Encode(v, e.options)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, s); err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.w, "\n")
+	return err
+}
+
+// Decoder 从一个 io.Reader 增量读取TOON文档，每次 Decode 只缓冲当前文档，
+// 不会把整个输入都读入内存，适合处理超大或多文档的TOON流。
+type Decoder struct {
+	lex    *lexer
+	parser *parser
+
+	arrCtx          arrayTokenContext // 用于在 Token() 里识别紧跟在 '[' 之后的表格头 '{'
+	newlineReported bool              // 是否已经为当前 token 之前的换行发出过 TokenRowEnd
+}
+
+// arrayTokenContext 跟踪 Token() 是否刚发出 TokenArrayStart，用来判断紧
+// 跟着的 '{'（或 '{' 前面的大小数字）是不是表格头，而不是数组里的一个
+// 普通对象元素——判断依据与 parser.parseArray 对 `[ N {k1, k2}:` 的识别
+// 方式一致：`{` 必须紧跟在 '[' 或 '[' 后的大小数字之后。
+type arrayTokenContext int
+
+const (
+	arrCtxNone   arrayTokenContext = iota
+	arrCtxOpened                   // 刚发出 TokenArrayStart
+)
+
+// NewDecoder 返回一个从 r 读取的 Decoder。
+func NewDecoder(r io.Reader) *Decoder {
+	lex := newLexerReader(r)
+	return &Decoder{
+		lex:    lex,
+		parser: newParser(lex),
+	}
+}
+
+// Decode 读取流中的下一个TOON值并存入 v 指向的变量，v 必须是非空指针。
+// 连续调用 Decode 可以依次读取同一个流里的多个文档。
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("gotoon: Decode requires a non-nil pointer, got %T", v)
+	}
+
+	if d.parser.cur.typ == tokenEOF {
+		return io.EOF
+	}
+
+	value, err := d.parser.parse()
+	if err != nil {
+		return err
+	}
+
+	elem := rv.Elem()
+	if value == nil {
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+
+	vv := reflect.ValueOf(value)
+	if elem.Kind() == reflect.Interface || vv.Type().AssignableTo(elem.Type()) {
+		elem.Set(vv)
+	} else if vv.Type().ConvertibleTo(elem.Type()) {
+		elem.Set(vv.Convert(elem.Type()))
+	} else {
+		return fmt.Errorf("gotoon: cannot decode %T into %s", value, elem.Type())
+	}
+
+	return nil
+}
+
+// Buffered 返回底层缓冲区里已经从 io.Reader 读出、但词法分析器尚未读取
+// 的数据。解析器为前瞻已经读出的当前及下一个 token（以及词法分析器回退
+// 的一个字符）不包含在内，所以它与 encoding/json 的 Decoder.Buffered 不
+// 同，返回的数据不是从上一个已解码值之后开始的。
+func (d *Decoder) Buffered() io.Reader {
+	n := d.lex.r.Buffered()
+	b, _ := d.lex.r.Peek(n)
+	return bytes.NewReader(b)
+}
+
+// TokenKind 标识 Token 返回的词法标记种类。
+type TokenKind int
+
+const (
+	TokenEOF           TokenKind = iota
+	TokenObjectStart             // {
+	TokenObjectEnd               // }
+	TokenArrayStart              // [
+	TokenArrayEnd                // ]
+	TokenTabularHeader           // 表格数组头部的 {k1, k2}: 片段，Value 是重建出的 "{k1, k2}:" 文本
+	TokenRow                     // 逗号分隔符（表格单元格之间、数组元素或对象字段之间的 ','）
+	TokenScalar                  // 标量（字符串/数字/布尔/null/裸标识符），以及对象键后的 ':'
+	TokenRowEnd                  // 两个 token 之间跨越的换行，标记表格数组里一行的结束
+)
+
+// Token 是 Decoder.Token 返回的一个最小词法单元。
+type Token struct {
+	Kind  TokenKind
+	Value string
+}
+
+// Token 返回底层词法分析器的下一个原始标记，供调用方在不具体化整个
+// []interface{} 的情况下，逐行处理大型表格数组。表格头（[ N {k1, k2}:）
+// 体现为一个单独的 TokenTabularHeader 标记；每一行的单元格以 TokenRow
+// 分隔，行与行之间以 TokenRowEnd 分隔，调用方据此就能增量地按行消费
+// 表格数组，而不必先把整个数组解析成 []interface{}。
+func (d *Decoder) Token() (Token, error) {
+	tok := d.parser.cur
+	if tok.typ == tokenEOF {
+		return Token{Kind: TokenEOF}, io.EOF
+	}
+
+	if tok.newlineBefore && !d.newlineReported {
+		d.newlineReported = true
+		return Token{Kind: TokenRowEnd}, nil
+	}
+	d.newlineReported = false
+
+	if tok.typ == tokenLBrace && d.arrCtx == arrCtxOpened {
+		d.arrCtx = arrCtxNone
+		return d.readTabularHeader(), nil
+	}
+
+	// `[ N {k1, k2}:` 里的大小数字 N 只是表格头的前缀，本身不携带调用方
+	// 需要的信息（行数可以按 TokenRowEnd 分隔出的数据行数出来），所以连同紧跟着的
+	// '{' 一起折叠进同一个 TokenTabularHeader，不把 N 单独当成一个容易
+	// 被误认成数组首个元素的 TokenScalar 吐出去。
+	if tok.typ == tokenNumber && d.arrCtx == arrCtxOpened && d.parser.peek.typ == tokenLBrace {
+		d.parser.nextToken()
+		d.arrCtx = arrCtxNone
+		return d.readTabularHeader(), nil
+	}
+
+	d.parser.nextToken()
+
+	switch tok.typ {
+	case tokenLBracket:
+		d.arrCtx = arrCtxOpened
+	default:
+		d.arrCtx = arrCtxNone
+	}
+
+	switch tok.typ {
+	case tokenLBrace:
+		return Token{Kind: TokenObjectStart, Value: tok.value}, nil
+	case tokenRBrace:
+		return Token{Kind: TokenObjectEnd, Value: tok.value}, nil
+	case tokenLBracket:
+		return Token{Kind: TokenArrayStart, Value: tok.value}, nil
+	case tokenRBracket:
+		return Token{Kind: TokenArrayEnd, Value: tok.value}, nil
+	case tokenColonColon:
+		return Token{Kind: TokenTabularHeader, Value: tok.value}, nil
+	case tokenComma:
+		return Token{Kind: TokenRow, Value: tok.value}, nil
+	default:
+		return Token{Kind: TokenScalar, Value: tok.value}, nil
+	}
+}
+
+// readTabularHeader 从当前的 '{' 开始读取表格头的键列表，直到匹配的 '}'
+// 以及紧随其后的 ':'，合并为一个 TokenTabularHeader，这样调用方看到的
+// 表格头是一个整体，不会被拆成一串 TokenObjectStart/TokenScalar。
+func (d *Decoder) readTabularHeader() Token {
+	var keys []string
+	d.parser.nextToken() // 跳过 '{'
+
+	for d.parser.cur.typ != tokenRBrace && d.parser.cur.typ != tokenEOF {
+		switch d.parser.cur.typ {
+		case tokenIdentifier:
+			keys = append(keys, d.parser.cur.value)
+		case tokenString:
+			key := d.parser.cur.value
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			}
+			keys = append(keys, key)
+		}
+		d.parser.nextToken()
+	}
+	if d.parser.cur.typ == tokenRBrace {
+		d.parser.nextToken() // 跳过 '}'
+	}
+	if d.parser.cur.typ == tokenColon {
+		d.parser.nextToken() // 跳过 ':'
+	}
+
+	return Token{Kind: TokenTabularHeader, Value: "{" + strings.Join(keys, ", ") + "}:"}
+}
@@ -0,0 +1,108 @@
+package gotoon
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderDecodesMultipleDocuments(t *testing.T) {
+	r := strings.NewReader("{a: 1}\n{b: 2}\n")
+	dec := NewDecoder(r)
+
+	var first, second map[string]interface{}
+	if err := dec.Decode(&first); err != nil {
+		t.Fatalf("decode first: %v", err)
+	}
+	if first["a"] != int64(1) {
+		t.Errorf("expected a=1, got %v", first)
+	}
+
+	if err := dec.Decode(&second); err != nil {
+		t.Fatalf("decode second: %v", err)
+	}
+	if second["b"] != int64(2) {
+		t.Errorf("expected b=2, got %v", second)
+	}
+
+	if err := dec.Decode(&second); err != io.EOF {
+		t.Errorf("expected io.EOF after last document, got %v", err)
+	}
+}
+
+func TestEncoderWritesMultipleDocuments(t *testing.T) {
+	var buf strings.Builder
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(map[string]interface{}{"a": 1}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := enc.Encode(map[string]interface{}{"b": 2}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	dec := NewDecoder(strings.NewReader(buf.String()))
+	var first, second map[string]interface{}
+	if err := dec.Decode(&first); err != nil || first["a"] != int64(1) {
+		t.Fatalf("decode first: %v %v", first, err)
+	}
+	if err := dec.Decode(&second); err != nil || second["b"] != int64(2) {
+		t.Fatalf("decode second: %v %v", second, err)
+	}
+}
+
+func TestDecoderTokenTabularHeaderAndRows(t *testing.T) {
+	input := `[ 2 {id, name}:
+  1, "Alice"
+  2, "Bob"
+]`
+	dec := NewDecoder(strings.NewReader(input))
+
+	var kinds []TokenKind
+	var header string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if tok.Kind == TokenTabularHeader {
+			header = tok.Value
+		}
+		kinds = append(kinds, tok.Kind)
+	}
+
+	if header != "{id, name}:" {
+		t.Errorf("expected header \"{id, name}:\", got %q", header)
+	}
+
+	rowEnds := 0
+	for _, k := range kinds {
+		if k == TokenRowEnd {
+			rowEnds++
+		}
+	}
+	if rowEnds != 3 {
+		t.Errorf("expected 3 row boundaries (after the header line, after row 1, and before the closing bracket), got %d in %v", rowEnds, kinds)
+	}
+}
+
+func TestDecoderTokenPlainArrayNotMistakenForHeader(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("{a: [1, 2]}"))
+
+	var kinds []TokenKind
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		kinds = append(kinds, tok.Kind)
+		if tok.Kind == TokenTabularHeader {
+			t.Fatalf("plain array must not produce a TokenTabularHeader, got %v", kinds)
+		}
+	}
+}
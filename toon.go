@@ -1,8 +1,10 @@
 package gotoon
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
 	"strconv"
@@ -13,11 +15,12 @@ import (
 
 // Options 配置选项
 type Options struct {
-	IndentSize     int    // 缩进大小
-	Delimiter      string // 列分隔符
-	UseTabular     bool   // 启用表格格式
-	KeyFolding     bool   // 启用键折叠
-	ShowArraySizes bool   // 显示数组大小
+	IndentSize       int    // 缩进大小
+	Delimiter        string // 列分隔符
+	UseTabular       bool   // 启用表格格式
+	KeyFolding       bool   // 启用键折叠
+	ShowArraySizes   bool   // 显示数组大小
+	PreserveComments bool   // EncodeAST 时是否输出AST节点上附带的注释
 }
 
 // DefaultOptions 返回默认配置选项
@@ -57,7 +60,9 @@ func EncodeJSON(jsonStr string, options Options) (string, error) {
 	return Encode(data, options)
 }
 
-// Decode 将TOON格式字符串解码为Go数据结构
+// Decode 将TOON格式字符串解码为Go数据结构。解析失败时返回的 error 底层
+// 是 ErrorList，聚合了同一次解析中遇到的全部问题及各自的位置，调用方
+// 可以用 errors.As 取回完整列表。
 func Decode(toonStr string) (interface{}, error) {
 	lexer := newLexer(toonStr)
 	parser := newParser(lexer)
@@ -80,6 +85,15 @@ func DecodeJSON(toonStr string) (string, error) {
 }
 
 func encodeValue(value interface{}, options Options, indent int, builder *strings.Builder) error {
+	if m, ok := value.(Marshaler); ok {
+		data, err := m.MarshalTOON()
+		if err != nil {
+			return err
+		}
+		builder.Write(data)
+		return nil
+	}
+
 	rv := reflect.ValueOf(value)
 	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
 		rv = rv.Elem()
@@ -90,6 +104,8 @@ func encodeValue(value interface{}, options Options, indent int, builder *string
 		return encodeMap(value, options, indent, builder)
 	case reflect.Slice, reflect.Array:
 		return encodeArray(value, options, indent, builder)
+	case reflect.Struct:
+		return encodeStruct(rv, options, indent, builder)
 	case reflect.String:
 		builder.WriteString(fmt.Sprintf("%q", rv.String()))
 	case reflect.Bool:
@@ -160,19 +176,96 @@ func encodeMap(value interface{}, options Options, indent int, builder *strings.
 	return nil
 }
 
+// encodeStruct 按 toon 标签把结构体当作 map 来编码，tabular 标签标记的
+// slice/array 字段会被强制以表格形式输出。
+func encodeStruct(rv reflect.Value, options Options, indent int, builder *strings.Builder) error {
+	fields := cachedTypeFields(rv.Type())
+
+	type item struct {
+		key     string
+		val     reflect.Value
+		tabular bool
+	}
+	items := make([]item, 0, len(fields))
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		if f.omitempty && isEmptyValue(fv) {
+			continue
+		}
+		items = append(items, item{key: f.name, val: fv, tabular: f.tabular})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].key < items[j].key })
+
+	writeIndent(builder, indent, options)
+	builder.WriteString("{")
+	if len(items) > 0 {
+		builder.WriteString("\n")
+	}
+
+	for i, it := range items {
+		writeIndent(builder, indent+options.IndentSize, options)
+		builder.WriteString(fmt.Sprintf("%s: ", it.key))
+
+		var err error
+		kind := it.val.Kind()
+		if it.tabular && (kind == reflect.Slice || kind == reflect.Array) {
+			err = encodeArrayOpts(it.val.Interface(), options, indent+options.IndentSize, builder, true)
+		} else {
+			err = encodeValue(it.val.Interface(), options, indent+options.IndentSize, builder)
+		}
+		if err != nil {
+			return err
+		}
+
+		if i < len(items)-1 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(items) > 0 {
+		writeIndent(builder, indent, options)
+	}
+	builder.WriteString("}")
+
+	return nil
+}
+
 func encodeArray(value interface{}, options Options, indent int, builder *strings.Builder) error {
+	return encodeArrayOpts(value, options, indent, builder, false)
+}
+
+// encodeArrayOpts 编码一个 slice/array。forceTabular 对应字段上的
+// `toon:"...,tabular"` 标签，即便 canUseTabularFormat 的启发式判断认为
+// 不适合，也强制使用表格格式。
+func encodeArrayOpts(value interface{}, options Options, indent int, builder *strings.Builder, forceTabular bool) error {
 	var slice []interface{}
 	rv := reflect.ValueOf(value)
 	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
 		slice = make([]interface{}, rv.Len())
 		for i := 0; i < rv.Len(); i++ {
-			slice[i] = rv.Index(i).Interface()
+			elem := rv.Index(i)
+			if _, ok := elem.Interface().(Marshaler); ok {
+				// 元素自定义了编码方式，交给 encodeValue 调用钩子，
+				// 不要把它展开成 map（那样会绕过 Marshaler）。
+				slice[i] = elem.Interface()
+				continue
+			}
+			ev := elem
+			for ev.Kind() == reflect.Ptr || ev.Kind() == reflect.Interface {
+				ev = ev.Elem()
+			}
+			if ev.Kind() == reflect.Struct {
+				slice[i] = structToMap(ev)
+			} else {
+				slice[i] = elem.Interface()
+			}
 		}
 	} else {
 		return fmt.Errorf("expected slice or array, got %T", value)
 	}
 
-	useTabular := options.UseTabular && canUseTabularFormat(slice)
+	useTabular := (options.UseTabular && canUseTabularFormat(slice)) || (forceTabular && len(slice) > 0 && hasMapElements(slice))
 
 	// 直接写入数组开始，不额外增加缩进
 	builder.WriteString("[")
@@ -183,6 +276,9 @@ func encodeArray(value interface{}, options Options, indent int, builder *string
 	if useTabular {
 		builder.WriteString("{")
 		keys := getCommonKeys(slice)
+		if len(keys) == 0 {
+			keys = getUnionKeys(slice)
+		}
 		builder.WriteString(strings.Join(keys, options.Delimiter))
 		builder.WriteString("}:\n")
 
@@ -283,6 +379,37 @@ func getCommonKeys(slice []interface{}) []string {
 	return keys
 }
 
+// getUnionKeys 返回 slice 里所有 map 元素键的并集，用于 `tabular` 标签
+// 强制表格输出、但各元素字段不完全一致（例如 omitempty）的场景。
+func getUnionKeys(slice []interface{}) []string {
+	union := make(map[string]bool)
+	for _, item := range slice {
+		itemMap, _ := item.(map[string]interface{})
+		for k := range itemMap {
+			union[k] = true
+		}
+	}
+
+	keys := make([]string, 0, len(union))
+	for k := range union {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// hasMapElements 判断 slice 里的元素是否都是 map[string]interface{}，
+// 只有这样才能用表格格式编码。
+func hasMapElements(slice []interface{}) bool {
+	for _, item := range slice {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func formatValue(value interface{}) string {
 	switch v := value.(type) {
 	case string:
@@ -330,35 +457,62 @@ const (
 
 // token 标记
 type token struct {
-	typ   tokenType
-	value string
-	pos   int
+	typ           tokenType
+	value         string
+	pos           int  // 起始字节偏移
+	line          int  // 起始行号，从1开始
+	col           int  // 起始列号，从1开始
+	newlineBefore bool // 紧邻的上一个 token 与它之间是否跨越了至少一个换行符
 }
 
-// lexer 词法分析器
+// position 把 token 的位置信息转换为一个 Position，供 ErrorList 使用。
+func (t token) position() Position {
+	return Position{Offset: t.pos, Line: t.line, Column: t.col}
+}
+
+// lexer 词法分析器，基于带缓冲的 rune 源实现，既可以包装一个完整的字符串，
+// 也可以包装一个 io.Reader 流，从而支持增量解码。
 type lexer struct {
-	input string
-	pos   int
-	start int
-	width int
+	r     *bufio.Reader
+	buf   []byte // 当前 token 已读取到的原始字节
+	start int    // 当前 token 起始的绝对字节偏移
+	pos   int    // 下一个待读字节的绝对偏移
+
+	line, col         int // 下一个待读字节的行列号，均从1开始
+	prevLine, prevCol int // 上一次 next() 调用前的行列号，供 backup() 还原
+	startLine         int // 当前 token 起始的行号
+	startCol          int // 当前 token 起始的列号
+
+	hasPending bool
+	pendingR   rune
+	pendingW   int
+
+	sawNewline bool // skipWhitespace() 期间是否跳过了至少一个换行符
 }
 
+// newLexer 从一个完整字符串构造词法分析器，兼容原有的一次性解码场景。
 func newLexer(input string) *lexer {
-	return &lexer{
-		input: input,
-	}
+	return newLexerReader(strings.NewReader(input))
+}
+
+// newLexerReader 从 io.Reader 构造词法分析器，只在读取当前 token 时才会
+// 触发底层 I/O，不会把整个输入都读入内存。
+func newLexerReader(r io.Reader) *lexer {
+	return &lexer{r: bufio.NewReader(r), line: 1, col: 1}
 }
 
 func (l *lexer) nextToken() token {
 	for {
 		l.skipWhitespace()
+		l.buf = l.buf[:0]
 		l.start = l.pos
+		l.startLine, l.startCol = l.line, l.col
 
-		if l.pos >= len(l.input) {
+		c := l.next()
+		if c == rune(-1) {
 			return l.emit(tokenEOF)
 		}
 
-		c := l.next()
 		switch c {
 		case '{':
 			return l.emit(tokenLBrace)
@@ -389,49 +543,128 @@ func (l *lexer) nextToken() token {
 	}
 }
 
+// next 读取下一个 rune，并把它追加到当前 token 的缓冲区里。
 func (l *lexer) next() rune {
-	if l.pos >= len(l.input) {
-		l.width = 0
-		return rune(-1)
-	}
-	r := rune(l.input[l.pos])
-	l.width = 1
-	if r >= 0x80 {
-		l.width = utf8.RuneLen(r)
-		if l.width < 0 {
-			l.width = 1
-		} else if l.pos+l.width > len(l.input) {
-			l.width = len(l.input) - l.pos
+	var r rune
+	var w int
+	if l.hasPending {
+		l.hasPending = false
+		r, w = l.pendingR, l.pendingW
+	} else {
+		var err error
+		r, w, err = l.r.ReadRune()
+		if err != nil {
+			return rune(-1)
 		}
 	}
-	l.pos += l.width
+
+	l.buf = utf8.AppendRune(l.buf, r)
+	l.pos += w
+	l.prevLine, l.prevCol = l.line, l.col
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
 	return r
 }
 
+// peek 返回下一个 rune 但不消费它，也不会把它计入当前 token。
 func (l *lexer) peek() rune {
-	r := l.next()
-	l.backup()
+	if l.hasPending {
+		return l.pendingR
+	}
+	r, _, err := l.r.ReadRune()
+	if err != nil {
+		return rune(-1)
+	}
+	l.r.UnreadRune()
 	return r
 }
 
+// backup 把最近一次 next() 读到的 rune 放回，供下一次 next() 重新读取。
+// 目前的语法只需要单个 rune 的前瞻/回退。
 func (l *lexer) backup() {
-	l.pos -= l.width
+	if len(l.buf) == 0 {
+		return
+	}
+	r, w := utf8.DecodeLastRune(l.buf)
+	l.buf = l.buf[:len(l.buf)-w]
+	l.pos -= w
+	l.hasPending = true
+	l.pendingR = r
+	l.pendingW = w
+	l.line, l.col = l.prevLine, l.prevCol
 }
 
 func (l *lexer) emit(typ tokenType) token {
-	tok := token{
-		typ:   typ,
-		value: l.input[l.start:l.pos],
-		pos:   l.start,
+	return token{
+		typ:           typ,
+		value:         string(l.buf),
+		pos:           l.start,
+		line:          l.startLine,
+		col:           l.startCol,
+		newlineBefore: l.sawNewline,
 	}
-	return tok
 }
 
+// skipWhitespace 跳过空白字符，以及 `#` 行注释和 `/* ... */` 块注释。
+// Decode/Unmarshal 只关心数据，注释和空白一样被直接丢弃；保留注释供
+// 回写使用是 gotoon/ast 包（ParseAST）的职责。
 func (l *lexer) skipWhitespace() {
+	l.sawNewline = false
+	for {
+		r := l.next()
+		if r == rune(-1) {
+			break
+		}
+		if unicode.IsSpace(r) {
+			if r == '\n' {
+				l.sawNewline = true
+			}
+			continue
+		}
+		if r == '#' {
+			l.skipLineComment()
+			continue
+		}
+		if r == '/' && l.peek() == '*' {
+			l.next() // 消费 '*'
+			l.skipBlockComment()
+			continue
+		}
+		l.backup()
+		break
+	}
+	l.buf = l.buf[:0]
+	l.start = l.pos
+}
+
+func (l *lexer) skipLineComment() {
 	for {
 		r := l.next()
-		if r == rune(-1) || !unicode.IsSpace(r) {
-			l.backup()
+		if r == rune(-1) {
+			break
+		}
+		if r == '\n' {
+			l.sawNewline = true
+			break
+		}
+	}
+}
+
+func (l *lexer) skipBlockComment() {
+	for {
+		r := l.next()
+		if r == rune(-1) {
+			break
+		}
+		if r == '\n' {
+			l.sawNewline = true
+		}
+		if r == '*' && l.peek() == '/' {
+			l.next()
 			break
 		}
 	}
@@ -451,50 +684,52 @@ func (l *lexer) scanString() token {
 }
 
 func (l *lexer) scanNumber() token {
-	if l.input[l.pos-1] == '-' {
-		l.next()
-	}
-
+	// 符号已经在 nextToken 里被 next() 消费并写入 buf
 	for {
-		if l.pos >= len(l.input) {
-			break
-		}
-		c := l.input[l.pos]
-		if c >= '0' && c <= '9' {
-			l.pos++
-			continue
-		}
-		if c == '.' {
-			l.pos++
-			for l.pos < len(l.input) && l.input[l.pos] >= '0' && l.input[l.pos] <= '9' {
-				l.pos++
+		r := l.peek()
+		switch {
+		case r >= '0' && r <= '9':
+			l.next()
+		case r == '.':
+			l.next()
+			for {
+				r = l.peek()
+				if r < '0' || r > '9' {
+					break
+				}
+				l.next()
 			}
-		}
-		if c == 'e' || c == 'E' {
-			l.pos++
-			if l.pos < len(l.input) && (l.input[l.pos] == '+' || l.input[l.pos] == '-') {
-				l.pos++
+		case r == 'e' || r == 'E':
+			l.next()
+			if r = l.peek(); r == '+' || r == '-' {
+				l.next()
 			}
-			for l.pos < len(l.input) && l.input[l.pos] >= '0' && l.input[l.pos] <= '9' {
-				l.pos++
+			for {
+				r = l.peek()
+				if r < '0' || r > '9' {
+					break
+				}
+				l.next()
 			}
+			return l.emit(tokenNumber)
+		default:
+			return l.emit(tokenNumber)
 		}
-		break
 	}
-
-	return l.emit(tokenNumber)
 }
 
 func (l *lexer) scanIdentifier() token {
 	for {
 		r := l.next()
 		if !isAlphaNumeric(r) {
-			l.backup()
+			if r != rune(-1) {
+				l.backup()
+			}
 			break
 		}
 	}
 
-	val := l.input[l.start:l.pos]
+	val := string(l.buf)
 	switch val {
 	case "true", "false":
 		return l.emit(tokenBoolean)
@@ -513,11 +748,15 @@ func isAlphaNumeric(c rune) bool {
 	return isAlpha(c) || (c >= '0' && c <= '9')
 }
 
-// parser 语法分析器
+// parser 语法分析器。解析过程中遇到的问题不会立即中断：它们被记录到
+// errs 里，解析在下一个同步点（, 、换行、} 或 ]）恢复，这样一次 parse()
+// 能报告同一份输入里的多个问题，而不是只报告第一个（做法仿照
+// go/parser 搭配 go/scanner.ErrorList 的方式）。
 type parser struct {
 	lexer *lexer
 	cur   token
 	peek  token
+	errs  ErrorList
 }
 
 func newParser(lexer *lexer) *parser {
@@ -532,11 +771,37 @@ func (p *parser) nextToken() {
 	p.peek = p.lexer.nextToken()
 }
 
+// errorf 记录一个解析问题，不中断当前调用栈，调用方在记录之后通常会
+// 调 recover() 寻找下一个同步点。
+func (p *parser) errorf(pos Position, format string, args ...interface{}) {
+	p.errs.Add(pos, fmt.Sprintf(format, args...))
+}
+
+// recover 跳过标记，直到下一个可能的同步点：逗号、换行、 } 、 ] 或文件
+// 结尾，随后返回，让调用方从那里继续解析。
+func (p *parser) recover() {
+	line := p.cur.line
+	for {
+		switch p.cur.typ {
+		case tokenEOF, tokenComma, tokenRBrace, tokenRBracket:
+			return
+		}
+		p.nextToken()
+		if p.cur.line != line {
+			return
+		}
+	}
+}
+
+// parse 解析一个完整的值，并把解析过程中累积的问题作为 ErrorList 返回
+// （非空时满足 error 接口，调用方可以用 errors.As 取回）。
 func (p *parser) parse() (interface{}, error) {
-	return p.parseValue()
+	p.errs = nil
+	v := p.parseValue()
+	return v, p.errs.Err()
 }
 
-func (p *parser) parseValue() (interface{}, error) {
+func (p *parser) parseValue() interface{} {
 	switch p.cur.typ {
 	case tokenLBrace:
 		return p.parseObject()
@@ -548,39 +813,47 @@ func (p *parser) parseValue() (interface{}, error) {
 			val = p.cur.value
 		}
 		p.nextToken()
-		return val, nil
+		return val
 	case tokenNumber:
 		if strings.Contains(p.cur.value, ".") || strings.Contains(p.cur.value, "e") || strings.Contains(p.cur.value, "E") {
 			val, _ := strconv.ParseFloat(p.cur.value, 64)
 			p.nextToken()
-			return val, nil
+			return val
 		}
 		val, _ := strconv.ParseInt(p.cur.value, 10, 64)
 		p.nextToken()
-		return val, nil
+		return val
 	case tokenBoolean:
 		val := p.cur.value == "true"
 		p.nextToken()
-		return val, nil
+		return val
 	case tokenNull:
 		p.nextToken()
-		return nil, nil
+		return nil
 	case tokenIdentifier:
 		val := p.cur.value
 		p.nextToken()
-		return val, nil
+		return val
 	default:
-		return nil, fmt.Errorf("unexpected token %v at position %d", p.cur.typ, p.cur.pos)
+		p.errorf(p.cur.position(), "unexpected token %v", p.cur.typ)
+		p.recover()
+		return nil
 	}
 }
 
-func (p *parser) parseObject() (map[string]interface{}, error) {
+func (p *parser) parseObject() map[string]interface{} {
 	obj := make(map[string]interface{})
+	open := p.cur.position()
 	p.nextToken() // 跳过 {
 
 	for p.cur.typ != tokenRBrace && p.cur.typ != tokenEOF {
 		if p.cur.typ != tokenIdentifier && p.cur.typ != tokenString {
-			return nil, fmt.Errorf("expected key at position %d", p.cur.pos)
+			p.errorf(p.cur.position(), "expected key, got %v", p.cur.typ)
+			p.recover()
+			if p.cur.typ == tokenComma {
+				p.nextToken()
+			}
+			continue
 		}
 
 		key := p.cur.value
@@ -590,16 +863,16 @@ func (p *parser) parseObject() (map[string]interface{}, error) {
 		p.nextToken()
 
 		if p.cur.typ != tokenColon {
-			return nil, fmt.Errorf("expected colon at position %d", p.cur.pos)
+			p.errorf(p.cur.position(), "expected ':', got %v", p.cur.typ)
+			p.recover()
+			if p.cur.typ == tokenComma {
+				p.nextToken()
+			}
+			continue
 		}
 		p.nextToken()
 
-		value, err := p.parseValue()
-		if err != nil {
-			return nil, err
-		}
-
-		obj[key] = value
+		obj[key] = p.parseValue()
 
 		if p.cur.typ == tokenComma {
 			p.nextToken()
@@ -607,20 +880,25 @@ func (p *parser) parseObject() (map[string]interface{}, error) {
 	}
 
 	if p.cur.typ != tokenRBrace {
-		return nil, fmt.Errorf("unclosed object")
+		p.errorf(open, "unclosed object")
+		return obj
 	}
 	p.nextToken() // 跳过 }
 
-	return obj, nil
+	return obj
 }
 
-func (p *parser) parseArray() (interface{}, error) {
+func (p *parser) parseArray() interface{} {
 	var arr []interface{}
+	open := p.cur.position()
 	p.nextToken() // 跳过 [
 
-	// 检查是否是表格格式
+	// 检查是否是表格格式。一个数字只有在它不是紧跟着逗号或 `]`（也就是
+	// 说它不是数组里的一个字面量元素）时，才当作大小前缀跳过——这样既
+	// 覆盖 tabular 头部的大小前缀，也覆盖普通数组（ShowArraySizes）的
+	// 大小前缀，同时不会把 `[1, 2, 3]`、`[3]` 这样的字面量数组误判。
 	var tableKeys []string
-	if p.cur.typ == tokenNumber {
+	if p.cur.typ == tokenNumber && p.peek.typ != tokenComma && p.peek.typ != tokenRBracket {
 		// 跳过数组大小
 		p.nextToken()
 	}
@@ -638,17 +916,12 @@ func (p *parser) parseArray() (interface{}, error) {
 
 	if len(tableKeys) > 0 {
 		// 解析表格格式数据
-		return p.parseTableFormat(tableKeys)
+		return p.parseTableFormat(tableKeys, open)
 	}
 
 	// 解析普通数组
 	for p.cur.typ != tokenRBracket && p.cur.typ != tokenEOF {
-		value, err := p.parseValue()
-		if err != nil {
-			return nil, err
-		}
-
-		arr = append(arr, value)
+		arr = append(arr, p.parseValue())
 
 		if p.cur.typ == tokenComma {
 			p.nextToken()
@@ -656,11 +929,12 @@ func (p *parser) parseArray() (interface{}, error) {
 	}
 
 	if p.cur.typ != tokenRBracket {
-		return nil, fmt.Errorf("unclosed array")
+		p.errorf(open, "unclosed array")
+		return arr
 	}
 	p.nextToken() // 跳过 ]
 
-	return arr, nil
+	return arr
 }
 
 func (p *parser) parseTableKeys() []string {
@@ -684,7 +958,7 @@ func (p *parser) parseTableKeys() []string {
 	return keys
 }
 
-func (p *parser) parseTableFormat(keys []string) ([]interface{}, error) {
+func (p *parser) parseTableFormat(keys []string, open Position) []interface{} {
 	var arr []interface{}
 
 	for p.cur.typ != tokenRBracket && p.cur.typ != tokenEOF {
@@ -704,12 +978,7 @@ func (p *parser) parseTableFormat(keys []string) ([]interface{}, error) {
 				break
 			}
 
-			value, err := p.parseValue()
-			if err != nil {
-				return nil, err
-			}
-
-			obj[key] = value
+			obj[key] = p.parseValue()
 
 			if i < len(keys)-1 && p.cur.typ == tokenComma {
 				p.nextToken()
@@ -721,6 +990,10 @@ func (p *parser) parseTableFormat(keys []string) ([]interface{}, error) {
 		}
 	}
 
+	if p.cur.typ != tokenRBracket {
+		p.errorf(open, "unclosed array")
+	}
+
 	// 找到数组结束符
 	for p.cur.typ != tokenRBracket && p.cur.typ != tokenEOF {
 		p.nextToken()
@@ -729,7 +1002,7 @@ func (p *parser) parseTableFormat(keys []string) ([]interface{}, error) {
 		p.nextToken() // 跳过 ]
 	}
 
-	return arr, nil
+	return arr
 }
 
 func isAllWhitespace(s string) bool {
@@ -2,6 +2,7 @@ package gotoon
 
 import (
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -108,6 +109,28 @@ func TestDecode(t *testing.T) {
     "id": 2,
     "name": "Bob"
   }
+]`,
+		},
+		{
+			name:  "plain array without size prefix",
+			input: `[1, 2, 3]`,
+			expected: `[
+  1,
+  2,
+  3
+]`,
+		},
+		{
+			name: "plain array with size prefix",
+			input: `[ 3
+  1,
+  2,
+  3
+]`,
+			expected: `[
+  1,
+  2,
+  3
 ]`,
 		},
 		{
@@ -156,6 +179,31 @@ func TestDecode(t *testing.T) {
 	}
 }
 
+func TestDecodeErrorListReportsAllProblems(t *testing.T) {
+	// 对象里有两个字段缺冒号，解析应当在第一个错误处恢复并继续报告第二个，
+	// 而不是在遇到第一个问题后就放弃。
+	_, err := Decode(`{
+  a: 1,
+  b 2,
+  c: 3,
+  d 4
+}`)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected error to be an ErrorList, got %T", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Pos.Line != 3 || errs[1].Pos.Line != 5 {
+		t.Errorf("expected errors on lines 3 and 5, got %d and %d", errs[0].Pos.Line, errs[1].Pos.Line)
+	}
+}
+
 func TestRoundTrip(t *testing.T) {
 	jsonStr := `{
   "name": "Test",